@@ -0,0 +1,208 @@
+package bugfixes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const DefaultBreakerThreshold = 5
+
+const DefaultBreakerCooldown = 30 * time.Second
+
+var ErrBreakerOpen = errors.New("bugfixes: circuit breaker open")
+
+// BreakerState is the position of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every send through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen short-circuits sends until the cool-down has passed.
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe through to test the endpoint.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker is a circuit breaker around an endpoint. It opens after threshold
+// consecutive failures and, once cooldown has passed, lets one probe through;
+// the probe's outcome closes or re-opens it.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreaker returns a closed breaker. Non-positive values fall back to the
+// defaults.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	if threshold <= 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow reports whether a send may go ahead. When the cool-down of an open
+// breaker has passed, the first caller becomes the half-open probe.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Record feeds the outcome of an allowed send back into the breaker. A
+// permanent rejection still proves the endpoint is up, so only transient
+// failures count against it; cancellation by the caller is ignored.
+func (b *Breaker) Record(err error) {
+	if errors.Is(err, context.Canceled) {
+		b.mu.Lock()
+		b.probing = false
+		b.mu.Unlock()
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil || permanent(err) {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// State returns the breaker's current position. An open breaker whose
+// cool-down has passed reports half-open.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*Breaker{}
+)
+
+// breakerFor returns the shared breaker for cfg's destination or server, or
+// nil if cfg disables the breaker. A custom Transport gets a breaker of its
+// own, so it neither trips nor is tripped by other transports.
+func breakerFor(cfg Config) *Breaker {
+	if cfg.BreakerThreshold < 0 {
+		return nil
+	}
+
+	cfg = cfg.normalized()
+	key := cfg.destination
+	if key == "" {
+		key = cfg.Server
+	}
+	if cfg.Transport != nil {
+		key += " (" + transportIdentity(cfg.Transport) + ")"
+	}
+
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	b, ok := breakers[key]
+	if !ok {
+		b = NewBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
+		breakers[key] = b
+	}
+
+	return b
+}
+
+// transportIdentity names t for breaker keys: its type, and its address
+// when it has one. Transports need not be comparable, so t itself cannot be
+// a map key; transports held by value share a breaker per type.
+func transportIdentity(t Transport) string {
+	v := reflect.ValueOf(t)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Func, reflect.Map, reflect.Chan, reflect.Slice, reflect.UnsafePointer:
+		return fmt.Sprintf("%T@%#x", t, v.Pointer())
+	}
+
+	return fmt.Sprintf("%T", t)
+}
+
+// BreakerState returns the state of the circuit breaker guarding cfg's
+// server, for use in health checks.
+func (c Config) BreakerState() BreakerState {
+	b := breakerFor(c)
+	if b == nil {
+		return BreakerClosed
+	}
+	return b.State()
+}
+
+// BreakerStates returns the state of every circuit breaker, keyed by server,
+// or by name for destinations. Breakers of custom transports have the
+// transport's type and address after the name, in parentheses.
+func BreakerStates() map[string]BreakerState {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	states := make(map[string]BreakerState, len(breakers))
+	for key, b := range breakers {
+		states[key] = b.State()
+	}
+
+	return states
+}
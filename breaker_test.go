@@ -0,0 +1,186 @@
+package bugfixes_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+// breakerServer returns a server name no other test run has used, since
+// breakers are shared per server for the life of the process.
+func breakerServer(t *testing.T) string {
+	t.Helper()
+	return fmt.Sprintf("https://%s-%d.example", t.Name(), time.Now().UnixNano())
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	b := bugfixes.NewBreaker(2, 20*time.Millisecond)
+	failure := errors.New("connection refused")
+
+	for range 2 {
+		if !b.Allow() {
+			t.Fatal("expected closed breaker to allow sends")
+		}
+		b.Record(failure)
+	}
+	if b.State() != bugfixes.BreakerOpen {
+		t.Fatalf("expected open after 2 failures, got %s", b.State())
+	}
+	if b.Allow() {
+		t.Fatal("expected open breaker to short-circuit")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if b.State() != bugfixes.BreakerHalfOpen {
+		t.Fatalf("expected half-open after cool-down, got %s", b.State())
+	}
+	if !b.Allow() {
+		t.Fatal("expected a probe to be allowed")
+	}
+	if b.Allow() {
+		t.Fatal("expected only one probe at a time")
+	}
+	b.Record(failure)
+	if b.State() != bugfixes.BreakerOpen {
+		t.Fatalf("expected failed probe to re-open, got %s", b.State())
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("expected a second probe to be allowed")
+	}
+	b.Record(nil)
+	if b.State() != bugfixes.BreakerClosed {
+		t.Fatalf("expected successful probe to close, got %s", b.State())
+	}
+}
+
+func TestBreakerIgnoresPermanentRejections(t *testing.T) {
+	b := bugfixes.NewBreaker(1, time.Hour)
+
+	b.Allow()
+	b.Record(&bugfixes.StatusError{StatusCode: http.StatusBadRequest})
+
+	if b.State() != bugfixes.BreakerClosed {
+		t.Fatalf("expected a 400 to leave the breaker closed, got %s", b.State())
+	}
+}
+
+func TestSendShortCircuitsWhileOpen(t *testing.T) {
+	var calls atomic.Int32
+	server := breakerServer(t)
+	cfg := bugfixes.Config{
+		Server: server,
+		Transport: bugfixes.TransportFunc(func(context.Context, bugfixes.Event) error {
+			calls.Add(1)
+			return errors.New("connection refused")
+		}),
+		RetryDeadline:    -1,
+		BreakerThreshold: 3,
+		BreakerCooldown:  time.Hour,
+	}
+
+	var err error
+	for range 10 {
+		err = bugfixes.Send(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`)))
+	}
+
+	if calls.Load() != 3 {
+		t.Fatalf("expected the transport to be called until the breaker opened, got %d calls", calls.Load())
+	}
+	if !errors.Is(err, bugfixes.ErrBreakerOpen) {
+		t.Fatalf("expected ErrBreakerOpen, got %v", err)
+	}
+	if cfg.BreakerState() != bugfixes.BreakerOpen {
+		t.Fatalf("expected open state, got %s", cfg.BreakerState())
+	}
+	var listed bool
+	for key, state := range bugfixes.BreakerStates() {
+		if strings.HasPrefix(key, server+" (") && state == bugfixes.BreakerOpen {
+			listed = true
+		}
+	}
+	if !listed {
+		t.Fatal("expected BreakerStates to include the open breaker")
+	}
+}
+
+func TestDeliverDivertsToFallbackWhileOpen(t *testing.T) {
+	fallback := bugfixes.NewRecorder()
+	cfg := bugfixes.Config{
+		Server: breakerServer(t),
+		Transport: bugfixes.TransportFunc(func(context.Context, bugfixes.Event) error {
+			return errors.New("connection refused")
+		}),
+		Fallback:         fallback,
+		RetryDeadline:    -1,
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Hour,
+	}
+
+	for range 3 {
+		_ = bugfixes.Deliver(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindBug, []byte(`{}`)))
+	}
+
+	if got := len(fallback.Events()); got != 3 {
+		t.Fatalf("expected every report diverted to the fallback, got %d", got)
+	}
+}
+
+func TestBreakerCanBeDisabled(t *testing.T) {
+	var calls atomic.Int32
+	cfg := bugfixes.Config{
+		Server: breakerServer(t),
+		Transport: bugfixes.TransportFunc(func(context.Context, bugfixes.Event) error {
+			calls.Add(1)
+			return errors.New("connection refused")
+		}),
+		RetryDeadline:    -1,
+		BreakerThreshold: -1,
+	}
+
+	for range 10 {
+		_ = bugfixes.Send(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`)))
+	}
+
+	if calls.Load() != 10 {
+		t.Fatalf("expected every send to reach the transport, got %d", calls.Load())
+	}
+}
+
+func TestBreakersAreKeptPerTransport(t *testing.T) {
+	failures := bugfixes.TransportFunc(func(context.Context, bugfixes.Event) error {
+		return errors.New("connection refused")
+	})
+	recorder := bugfixes.NewRecorder()
+	failing := bugfixes.NewClient(bugfixes.Config{Transport: failures, RetryDeadline: -1, BreakerThreshold: 1})
+	healthy := bugfixes.NewClient(bugfixes.Config{Transport: recorder, RetryDeadline: -1, BreakerThreshold: 1})
+	t.Cleanup(func() {
+		_ = failing.Close(context.Background())
+		_ = healthy.Close(context.Background())
+	})
+
+	if err := bugfixes.Send(context.Background(), failing.Config(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))); err == nil {
+		t.Fatal("expected the failing transport to fail")
+	}
+	if state := failing.Config().BreakerState(); state != bugfixes.BreakerOpen {
+		t.Fatalf("expected the failing transport's breaker to open, got %s", state)
+	}
+
+	if state := healthy.Config().BreakerState(); state != bugfixes.BreakerClosed {
+		t.Fatalf("expected another transport's breaker to stay closed, got %s", state)
+	}
+	if err := bugfixes.Send(context.Background(), healthy.Config(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))); err != nil {
+		t.Fatalf("expected the healthy transport to send, got %v", err)
+	}
+	if got := len(recorder.Events()); got != 1 {
+		t.Fatalf("expected one recorded event, got %d", got)
+	}
+}
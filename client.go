@@ -0,0 +1,210 @@
+package bugfixes
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Client reports events with its own config, delivery queues, sampler and
+// deduplication state, so several clients with different credentials can
+// run in one process without affecting each other. The package-level
+// functions use DefaultClient.
+//
+// Circuit breakers and spools are shared by all clients, keyed by server and
+// spool directory. Spooled reports are replayed with the config of the
+// client that spooled them. Delivery statistics are kept per client and
+// summed by Stats.
+type Client struct {
+	configMu sync.RWMutex
+	config   *Config
+
+	queueMu           sync.Mutex
+	queue             *Queue
+	destinationQueues map[string]*Queue
+
+	sampler sampler
+	deduper deduper
+	stats   statsRecorder
+}
+
+var defaultClient = &Client{}
+
+// DefaultClient returns the client used by the package-level functions. Its
+// config is read from the environment until SetDefaultConfig is called.
+func DefaultClient() *Client {
+	return defaultClient
+}
+
+// NewClient returns a client that reports with cfg.
+func NewClient(cfg Config) *Client {
+	c := &Client{}
+	_ = c.setConfig(cfg, false)
+
+	return c
+}
+
+// Config returns the client's config. Configs derived from it with Merge
+// report through this client.
+func (c *Client) Config() Config {
+	c.configMu.RLock()
+	stored := c.config
+	c.configMu.RUnlock()
+
+	var cfg Config
+	if stored != nil {
+		cfg = *stored
+	} else {
+		cfg = LoadConfigFromEnv()
+	}
+	cfg.client = c
+
+	return cfg.normalized()
+}
+
+// SetConfig replaces the client's config. With cfg.Strict set, a config that
+// fails Validate is refused and the error returned; otherwise the error is
+// always nil.
+func (c *Client) SetConfig(cfg Config) error {
+	return c.setConfig(cfg, cfg.Strict)
+}
+
+func (c *Client) setConfig(cfg Config, strict bool) error {
+	if strict {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+	}
+	cfg.client = nil
+	cfg = cfg.normalized()
+
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	c.config = &cfg
+
+	return nil
+}
+
+func (c *Client) resetConfig() {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	c.config = nil
+}
+
+// Submit queues event for delivery with the client's config, as the
+// package-level Submit does.
+func (c *Client) Submit(event Event) error {
+	return Submit(c.Config(), event)
+}
+
+// ReportContext delivers event synchronously with the client's config, as
+// the package-level ReportContext does.
+func (c *Client) ReportContext(ctx context.Context, event Event) (Result, error) {
+	return ReportContext(ctx, c.Config(), event)
+}
+
+// Queue returns the client's delivery queue, starting it from the client's
+// config on first use.
+func (c *Client) Queue() *Queue {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	if c.queue == nil {
+		cfg := c.Config()
+		c.queue = NewQueue(cfg.QueueSize, cfg.QueueWorkers, cfg.DropPolicy)
+		c.queue.onDrop = statsFor(cfg).drop
+	}
+
+	return c.queue
+}
+
+// destinationQueue returns the queue for cfg's destination, starting it on
+// first use.
+func (c *Client) destinationQueue(cfg Config) *Queue {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	if c.destinationQueues == nil {
+		c.destinationQueues = make(map[string]*Queue)
+	}
+	q, ok := c.destinationQueues[cfg.destination]
+	if !ok {
+		q = NewQueue(cfg.QueueSize, cfg.QueueWorkers, cfg.DropPolicy)
+		q.onDrop = statsFor(cfg).drop
+		c.destinationQueues[cfg.destination] = q
+	}
+
+	return q
+}
+
+// queues returns the client's started queues.
+func (c *Client) queues() []*Queue {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	queues := make([]*Queue, 0, len(c.destinationQueues)+1)
+	if c.queue != nil {
+		queues = append(queues, c.queue)
+	}
+	for _, q := range c.destinationQueues {
+		queues = append(queues, q)
+	}
+
+	return queues
+}
+
+// Queued returns the number of reports waiting in the client's queues.
+func (c *Client) Queued() int {
+	queued := 0
+	for _, q := range c.queues() {
+		queued += q.Len()
+	}
+
+	return queued
+}
+
+// Flush waits for the client's queues to drain.
+func (c *Client) Flush(ctx context.Context) error {
+	var errs []error
+	for _, q := range c.queues() {
+		errs = append(errs, q.Flush(ctx))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close sends the counts of repeats still held back by deduplication,
+// drains and stops the client's queues, then stops replaying its spooled
+// reports, sealing each spool no other client writes to. Reports made
+// afterwards start fresh queues.
+func (c *Client) Close(ctx context.Context) error {
+	c.deduper.flush()
+
+	c.queueMu.Lock()
+	queue := c.queue
+	destinations := c.destinationQueues
+	c.queue = nil
+	c.destinationQueues = nil
+	c.queueMu.Unlock()
+
+	var errs []error
+	if queue != nil {
+		errs = append(errs, queue.Close(ctx))
+	}
+	for _, q := range destinations {
+		errs = append(errs, q.Close(ctx))
+	}
+
+	errs = append(errs, releaseSpools(c))
+
+	return errors.Join(errs...)
+}
+
+// owner returns the client c reports through.
+func (c Config) owner() *Client {
+	if c.client != nil {
+		return c.client
+	}
+
+	return defaultClient
+}
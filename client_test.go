@@ -0,0 +1,123 @@
+package bugfixes_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func TestClientsAreIsolated(t *testing.T) {
+	first := bugfixes.NewRecorder()
+	second := bugfixes.NewRecorder()
+	a := bugfixes.NewClient(bugfixes.Config{Transport: first, AgentKey: "tenant-a"})
+	b := bugfixes.NewClient(bugfixes.Config{Transport: second, AgentKey: "tenant-b"})
+	t.Cleanup(func() {
+		_ = a.Close(context.Background())
+		_ = b.Close(context.Background())
+	})
+
+	for _, client := range []*bugfixes.Client{a, b, a} {
+		event := bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))
+		event.Level = bugfixes.ERROR
+		event.Message = uniqueKey(t)
+		if err := client.Submit(event); err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+	for _, client := range []*bugfixes.Client{a, b} {
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+	}
+
+	if got := len(first.Events()); got != 2 {
+		t.Fatalf("expected both of the first client's events, got %d", got)
+	}
+	if got := len(second.Events()); got != 1 {
+		t.Fatalf("expected the second client's event only, got %d", got)
+	}
+	if a.Config().AgentKey != "tenant-a" || b.Config().AgentKey != "tenant-b" {
+		t.Fatalf("expected each client to keep its own config, got %q and %q", a.Config().AgentKey, b.Config().AgentKey)
+	}
+}
+
+func TestClientDeduplicatesOnItsOwn(t *testing.T) {
+	first := bugfixes.NewRecorder()
+	second := bugfixes.NewRecorder()
+	a := bugfixes.NewClient(bugfixes.Config{Transport: first, DedupWindow: time.Minute})
+	b := bugfixes.NewClient(bugfixes.Config{Transport: second, DedupWindow: time.Minute})
+	t.Cleanup(func() {
+		_ = a.Close(context.Background())
+		_ = b.Close(context.Background())
+	})
+
+	level := uniqueKey(t)
+	for _, client := range []*bugfixes.Client{a, a, b} {
+		event := bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))
+		event.Level = level
+		event.Message = "same message"
+		if err := client.Submit(event); err != nil && !errors.Is(err, bugfixes.ErrDropped) {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+	_ = a.Flush(context.Background())
+	_ = b.Flush(context.Background())
+
+	if len(first.Events()) != 1 || len(second.Events()) != 1 {
+		t.Fatalf("expected one event per client, got %d and %d", len(first.Events()), len(second.Events()))
+	}
+}
+
+func TestMergedConfigKeepsClient(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	client := bugfixes.NewClient(bugfixes.Config{Transport: recorder})
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	cfg := client.Config().Merge(bugfixes.Config{LogLevel: bugfixes.ERROR})
+	submitLevel(t, cfg, bugfixes.ERROR)
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	if got := len(recorder.Events()); got != 1 {
+		t.Fatalf("expected the merged config to report through the client, got %d", got)
+	}
+}
+
+func TestDefaultClientRereadsEnvironment(t *testing.T) {
+	clearConfigEnv(t)
+	t.Cleanup(bugfixes.ResetDefaultConfig)
+	bugfixes.ResetDefaultConfig()
+
+	t.Setenv("BUGFIXES_LOG_LEVELS", "github.com/acme/billing/...=debug")
+	first := bugfixes.GetDefaultConfig().LogLevels
+	if got := bugfixes.GetDefaultConfig().LogLevels; got != first {
+		t.Fatal("expected unchanged level rules to be parsed once")
+	}
+
+	t.Setenv("BUGFIXES_LOG_LEVEL", "warn")
+	t.Setenv("BUGFIXES_LOG_LEVELS", "*=error")
+	cfg := bugfixes.GetDefaultConfig()
+	if cfg.LogLevel != bugfixes.WARN {
+		t.Fatalf("expected a changed environment to be read, got %q", cfg.LogLevel)
+	}
+	if got := cfg.LogLevels.String(); got != "*=error" {
+		t.Fatalf("expected the changed rules, got %q", got)
+	}
+}
+
+func BenchmarkDefaultConfigFromEnv(b *testing.B) {
+	b.Setenv("BUGFIXES_LOG_LEVELS", "github.com/acme/billing/...=debug,github.com/acme/api=warn")
+	b.Setenv("BUGFIXES_SAMPLE_RATES", "debug=0.01,info=0.1")
+	bugfixes.ResetDefaultConfig()
+	b.Cleanup(bugfixes.ResetDefaultConfig)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		cfg := bugfixes.GetDefaultConfig()
+		_ = cfg.LevelFor("github.com/acme/billing/invoice", "invoice.go")
+	}
+}
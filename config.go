@@ -1,12 +1,8 @@
 package bugfixes
 
 import (
-	"fmt"
 	"net/http"
-	"os"
-	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -23,59 +19,121 @@ type Config struct {
 	LogLevel    string
 	LocalOnly   bool
 	HTTPClient  *http.Client
-}
 
-var (
-	defaultConfigMu sync.RWMutex
-	defaultConfig   *Config
-)
+	// LogLevels overrides LogLevel for matching packages and files.
+	LogLevels *LevelRules
 
-func LoadConfigFromEnv() Config {
-	localOnlyStr := strings.TrimSpace(os.Getenv("BUGFIXES_LOCAL_ONLY"))
-	localOnly, err := strconv.ParseBool(localOnlyStr)
-	if err != nil && localOnlyStr != "" {
-		_, _ = fmt.Fprintf(os.Stderr, "bugfixes: invalid BUGFIXES_LOCAL_ONLY value %q, defaulting to false\n", localOnlyStr)
+	// Service, Environment and Release identify what sent a report. Release
+	// defaults to DefaultRelease.
+	Service     string
+	Environment string
+	Release     string
 
-	}
+	// SignRequests authenticates with an HMAC signature rather than sending
+	// AgentSecret on every request.
+	SignRequests bool
 
-	return Config{
-		Server:      valueOrDefault(os.Getenv("BUGFIXES_SERVER"), DefaultServer),
-		AgentKey:    os.Getenv("BUGFIXES_AGENT_KEY"),
-		AgentSecret: os.Getenv("BUGFIXES_AGENT_SECRET"),
-		LogLevel:    os.Getenv("BUGFIXES_LOG_LEVEL"),
-		LocalOnly:   localOnly,
-	}
-}
+	// Compress gzips request bodies of at least CompressThreshold bytes.
+	Compress          bool
+	CompressThreshold int
 
-func GetDefaultConfig() Config {
-	defaultConfigMu.RLock()
-	cfg := defaultConfig
-	defaultConfigMu.RUnlock()
-	if cfg != nil {
-		return cfg.normalized()
-	}
+	// Transport delivers reports. Nil means an HTTPTransport built from the
+	// fields above.
+	Transport Transport
+
+	// Fallback receives reports that could not be delivered through
+	// Transport, including those short-circuited by an open breaker.
+	Fallback Transport
+
+	// Delivery queue, read when the default queue is first started.
+	QueueSize    int
+	QueueWorkers int
+	DropPolicy   DropPolicy
+
+	// Retries. A negative RetryDeadline sends each report exactly once.
+	RetryDeadline       time.Duration
+	RetryInitialBackoff time.Duration
+	RetryMaxBackoff     time.Duration
+
+	// Spool. When SpoolDir is set, reports that cannot be delivered are
+	// written there and replayed in the background.
+	SpoolDir            string
+	SpoolMaxBytes       int64
+	SpoolMaxAge         time.Duration
+	SpoolReplayInterval time.Duration
+
+	// Circuit breaker. It opens after BreakerThreshold consecutive failures
+	// and probes again after BreakerCooldown. A negative threshold disables
+	// it.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// DedupWindow suppresses repeats of an event with the same fingerprint
+	// for this long; the next report sent carries the occurrence count.
+	// Zero disables deduplication.
+	DedupWindow time.Duration
+
+	// Sampling, keyed by level. SampleRates holds the fraction of events
+	// sent, between 0 and 1; levels without a rate are always sent. Rate
+	// limits apply per level and to each fingerprint.
+	SampleRates          map[string]float64
+	LevelRateLimits      map[string]RateLimit
+	FingerprintRateLimit RateLimit
+
+	// OnInternalError receives the library's own failures, such as missing
+	// credentials or a report that could not be delivered. When nil they
+	// are printed to stderr, at most once a minute per message.
+	OnInternalError func(error)
+
+	// Strict makes SetDefaultConfig and Client.SetConfig refuse a config
+	// that fails Validate.
+	Strict bool
 
-	return LoadConfigFromEnv().normalized()
+	// Destinations fans reports out to several endpoints. When set, they
+	// replace Server, the credentials, Transport, Fallback, the signing and
+	// compression options and SpoolDir above.
+	Destinations []Destination
+
+	// destination and minLevel are set on the per-destination configs
+	// derived from Destinations.
+	destination string
+	minLevel    string
+
+	// client is the Client whose queues and sampling state the config
+	// reports through. Nil means DefaultClient.
+	client *Client
 }
 
-func SetDefaultConfig(cfg Config) {
-	cfg = cfg.normalized()
+func LoadConfigFromEnv() Config {
+	return Config{Server: DefaultServer}.apply(envSettings(), SourceEnv, nil)
+}
 
-	defaultConfigMu.Lock()
-	defer defaultConfigMu.Unlock()
+// GetDefaultConfig returns the config of DefaultClient.
+func GetDefaultConfig() Config {
+	return defaultClient.Config()
+}
 
-	defaultConfig = &cfg
+// SetDefaultConfig replaces the config of DefaultClient. With cfg.Strict
+// set, a config that fails Validate is refused, the previous one kept and
+// the error passed to ReportInternalError. Use DefaultClient().SetConfig to
+// get the error back instead.
+func SetDefaultConfig(cfg Config) {
+	if err := defaultClient.SetConfig(cfg); err != nil {
+		ReportInternalError(cfg, err)
+	}
 }
 
+// ResetDefaultConfig makes DefaultClient read its config from the
+// environment again.
 func ResetDefaultConfig() {
-	defaultConfigMu.Lock()
-	defer defaultConfigMu.Unlock()
-
-	defaultConfig = nil
+	defaultClient.resetConfig()
 }
 
 func (c Config) Merge(override Config) Config {
 	merged := c
+	if merged.client == nil {
+		merged.client = override.client
+	}
 
 	if override.Server != "" {
 		merged.Server = override.Server
@@ -89,12 +147,96 @@ func (c Config) Merge(override Config) Config {
 	if override.LogLevel != "" {
 		merged.LogLevel = override.LogLevel
 	}
+	if override.LogLevels != nil {
+		merged.LogLevels = override.LogLevels
+	}
 	if override.LocalOnly {
 		merged.LocalOnly = true
 	}
 	if override.HTTPClient != nil {
 		merged.HTTPClient = override.HTTPClient
 	}
+	if override.Service != "" {
+		merged.Service = override.Service
+	}
+	if override.Environment != "" {
+		merged.Environment = override.Environment
+	}
+	if override.Release != "" {
+		merged.Release = override.Release
+	}
+	if override.SignRequests {
+		merged.SignRequests = true
+	}
+	if override.Compress {
+		merged.Compress = true
+	}
+	if override.CompressThreshold != 0 {
+		merged.CompressThreshold = override.CompressThreshold
+	}
+	if override.Transport != nil {
+		merged.Transport = override.Transport
+	}
+	if override.Fallback != nil {
+		merged.Fallback = override.Fallback
+	}
+	if override.QueueSize != 0 {
+		merged.QueueSize = override.QueueSize
+	}
+	if override.QueueWorkers != 0 {
+		merged.QueueWorkers = override.QueueWorkers
+	}
+	if override.DropPolicy != DropNewest {
+		merged.DropPolicy = override.DropPolicy
+	}
+	if override.RetryDeadline != 0 {
+		merged.RetryDeadline = override.RetryDeadline
+	}
+	if override.RetryInitialBackoff != 0 {
+		merged.RetryInitialBackoff = override.RetryInitialBackoff
+	}
+	if override.RetryMaxBackoff != 0 {
+		merged.RetryMaxBackoff = override.RetryMaxBackoff
+	}
+	if override.SpoolDir != "" {
+		merged.SpoolDir = override.SpoolDir
+	}
+	if override.SpoolMaxBytes != 0 {
+		merged.SpoolMaxBytes = override.SpoolMaxBytes
+	}
+	if override.SpoolMaxAge != 0 {
+		merged.SpoolMaxAge = override.SpoolMaxAge
+	}
+	if override.SpoolReplayInterval != 0 {
+		merged.SpoolReplayInterval = override.SpoolReplayInterval
+	}
+	if override.BreakerThreshold != 0 {
+		merged.BreakerThreshold = override.BreakerThreshold
+	}
+	if override.BreakerCooldown != 0 {
+		merged.BreakerCooldown = override.BreakerCooldown
+	}
+	if override.DedupWindow != 0 {
+		merged.DedupWindow = override.DedupWindow
+	}
+	if override.SampleRates != nil {
+		merged.SampleRates = override.SampleRates
+	}
+	if override.LevelRateLimits != nil {
+		merged.LevelRateLimits = override.LevelRateLimits
+	}
+	if override.FingerprintRateLimit != (RateLimit{}) {
+		merged.FingerprintRateLimit = override.FingerprintRateLimit
+	}
+	if override.OnInternalError != nil {
+		merged.OnInternalError = override.OnInternalError
+	}
+	if override.Destinations != nil {
+		merged.Destinations = override.Destinations
+	}
+	if override.Strict {
+		merged.Strict = true
+	}
 
 	return merged.normalized()
 }
@@ -108,26 +250,40 @@ func (c Config) GetHTTPClient() *http.Client {
 	return defaultHTTPClient
 }
 
+// GetTransport returns the configured transport, or an HTTPTransport for
+// the configured server and credentials.
+func (c Config) GetTransport() Transport {
+	if c.Transport != nil {
+		return c.Transport
+	}
+	return NewHTTPTransport(c)
+}
+
 func (c Config) LogEndpoint() string {
-	return strings.TrimRight(c.normalized().Server, "/") + "/log"
+	return c.endpoint(KindLog)
 }
 
 func (c Config) BugEndpoint() string {
-	return strings.TrimRight(c.normalized().Server, "/") + "/bug"
+	return c.endpoint(KindBug)
+}
+
+func (c Config) endpoint(kind string) string {
+	return strings.TrimRight(c.normalized().Server, "/") + "/" + kind
 }
 
 func (c Config) normalized() Config {
 	if c.Server == "" {
 		c.Server = DefaultServer
 	}
-
-	return c
-}
-
-func valueOrDefault(value, fallback string) string {
-	if value == "" {
-		return fallback
+	if c.RetryDeadline == 0 {
+		c.RetryDeadline = DefaultRetryDeadline
+	}
+	if c.RetryInitialBackoff <= 0 {
+		c.RetryInitialBackoff = DefaultRetryInitialBackoff
+	}
+	if c.RetryMaxBackoff <= 0 {
+		c.RetryMaxBackoff = DefaultRetryMaxBackoff
 	}
 
-	return value
+	return c
 }
@@ -1,7 +1,11 @@
 package bugfixes_test
 
 import (
+	"errors"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	bugfixes "github.com/bugfixes/go-bugfixes"
 )
@@ -70,3 +74,76 @@ func TestSetDefaultConfig(t *testing.T) {
 		t.Fatalf("expected configured secret, got %q", cfg.AgentSecret)
 	}
 }
+
+func TestConfigValidate(t *testing.T) {
+	valid := bugfixes.Config{
+		Server:      "https://bugfixes.example/v1",
+		AgentKey:    "key",
+		AgentSecret: "secret",
+		LogLevel:    bugfixes.WARN,
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected a valid config, got %v", err)
+	}
+	if err := (bugfixes.Config{LogLevel: "5"}).Validate(); err != nil {
+		t.Fatalf("expected a numeric level to be valid, got %v", err)
+	}
+
+	invalid := bugfixes.Config{
+		Server:      "ftp://bugfixes.example",
+		AgentKey:    "key",
+		LogLevel:    "ERROR",
+		HTTPClient:  &http.Client{Timeout: -time.Second},
+		SampleRates: map[string]float64{bugfixes.INFO: 2},
+		Destinations: []bugfixes.Destination{
+			{Name: "mirror", Server: "bugfixes.example", AgentKey: "key", AgentSecret: "secret"},
+		},
+	}
+	err := invalid.Validate()
+	if !errors.Is(err, bugfixes.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got %T", err)
+	}
+	if got := len(joined.Unwrap()); got != 6 {
+		t.Fatalf("expected every problem to be reported, got %d: %v", got, err)
+	}
+	for _, want := range []string{"http or https", "BUGFIXES_AGENT_SECRET", `"ERROR" is not a level`, "timeout", "sample rate", "mirror Server"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestSetDefaultConfigStrict(t *testing.T) {
+	t.Cleanup(bugfixes.ResetDefaultConfig)
+
+	bugfixes.SetDefaultConfig(bugfixes.Config{Server: "https://accepted.example"})
+
+	var reported error
+	bugfixes.SetDefaultConfig(bugfixes.Config{
+		Server:          "https://refused.example",
+		LogLevel:        "loud",
+		Strict:          true,
+		OnInternalError: func(err error) { reported = err },
+	})
+	if !errors.Is(reported, bugfixes.ErrInvalidConfig) {
+		t.Fatalf("expected strict mode to report the refused config, got %v", reported)
+	}
+	if got := bugfixes.GetDefaultConfig().Server; got != "https://accepted.example" {
+		t.Fatalf("expected the previous default to be kept, got %q", got)
+	}
+
+	err := bugfixes.DefaultClient().SetConfig(bugfixes.Config{LogLevel: "loud", Strict: true})
+	if !errors.Is(err, bugfixes.ErrInvalidConfig) {
+		t.Fatalf("expected SetConfig to return the refusal, got %v", err)
+	}
+
+	bugfixes.SetDefaultConfig(bugfixes.Config{LogLevel: "loud"})
+	if got := bugfixes.GetDefaultConfig().LogLevel; got != "loud" {
+		t.Fatalf("expected a non-strict config to be accepted, got %q", got)
+	}
+}
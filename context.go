@@ -0,0 +1,80 @@
+package bugfixes
+
+import (
+	"context"
+	"maps"
+)
+
+// ContextKey is the type of the context keys reports read metadata from.
+type ContextKey int
+
+const (
+	// RequestIDKey holds the request ID, as set by middleware.RequestID.
+	RequestIDKey ContextKey = iota
+	// TraceIDKey holds the trace ID set by WithTraceID.
+	TraceIDKey
+	// TagsKey holds the tags set by WithTags.
+	TagsKey
+)
+
+// WithTraceID returns a copy of ctx carrying a trace ID for reports.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, TraceIDKey, traceID)
+}
+
+// WithTags returns a copy of ctx whose report tags are those already in ctx
+// plus tags, which win on conflict.
+func WithTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := maps.Clone(TagsFromContext(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(tags))
+	}
+	maps.Copy(merged, tags)
+
+	return context.WithValue(ctx, TagsKey, merged)
+}
+
+// RequestIDFromContext returns the request ID in ctx, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// TraceIDFromContext returns the trace ID in ctx, or "".
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(TraceIDKey).(string)
+	return id
+}
+
+// TagsFromContext returns the tags in ctx. The map must not be modified.
+func TagsFromContext(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	tags, _ := ctx.Value(TagsKey).(map[string]string)
+	return tags
+}
+
+// WithContext returns a copy of e carrying the request ID, trace ID and tags
+// found in ctx. Values already set on e are kept.
+func (e Event) WithContext(ctx context.Context) Event {
+	if e.RequestID == "" {
+		e.RequestID = RequestIDFromContext(ctx)
+	}
+	if e.TraceID == "" {
+		e.TraceID = TraceIDFromContext(ctx)
+	}
+	if tags := TagsFromContext(ctx); len(tags) > 0 {
+		merged := maps.Clone(tags)
+		maps.Copy(merged, e.Tags)
+		e.Tags = merged
+	}
+
+	return e
+}
@@ -0,0 +1,57 @@
+package bugfixes_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func TestEventWithContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), bugfixes.RequestIDKey, "req-1")
+	ctx = bugfixes.WithTraceID(ctx, "trace-1")
+	ctx = bugfixes.WithTags(ctx, map[string]string{"tenant": "acme", "region": "eu"})
+	ctx = bugfixes.WithTags(ctx, map[string]string{"region": "us"})
+
+	event := bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))
+	event.Tags = map[string]string{"tenant": "override"}
+	event = event.WithContext(ctx)
+
+	if event.RequestID != "req-1" || event.TraceID != "trace-1" {
+		t.Fatalf("expected request and trace IDs from the context, got %q and %q", event.RequestID, event.TraceID)
+	}
+	if event.Tags["region"] != "us" || event.Tags["tenant"] != "override" {
+		t.Fatalf("expected later and event tags to win, got %v", event.Tags)
+	}
+
+	body, err := event.Body()
+	if err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	for _, want := range []string{`"request_id":"req-1"`, `"trace_id":"trace-1"`, `"tags":{`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %s in %s", want, body)
+		}
+	}
+}
+
+func TestSendCapsLongerCallerDeadline(t *testing.T) {
+	server, _ := statusSequence(t, http.StatusServiceUnavailable)
+
+	cfg := retryConfig(server.URL)
+	cfg.RetryDeadline = 100 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	if err := bugfixes.Send(ctx, cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))); err == nil {
+		t.Fatal("expected the 503 to be returned")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected RetryDeadline to cap the retries, took %v", elapsed)
+	}
+}
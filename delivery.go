@@ -0,0 +1,327 @@
+package bugfixes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const DefaultRetryDeadline = 30 * time.Second
+
+const DefaultRetryInitialBackoff = 500 * time.Millisecond
+
+const DefaultRetryMaxBackoff = 10 * time.Second
+
+// StatusError is returned when the API answers with a non-2xx status.
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("bugfixes: unexpected status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Is makes every StatusError match ErrStatus.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrStatus
+}
+
+// Retryable reports whether the status is worth trying again: 429 and any
+// 5xx are, other 4xx are permanent.
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Retryable reports whether a delivery error is transient. Status errors are
+// classified by code; cancellation of the caller's context, an open circuit
+// breaker, and failures to encode the report or build the request end the
+// attempt; anything else is treated as a network failure and retried.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrBreakerOpen) || localFailure(err) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Retryable()
+	}
+
+	return true
+}
+
+// localFailure reports whether err happened before anything was sent, in a
+// way that trying again cannot fix.
+func localFailure(err error) bool {
+	return errors.Is(err, ErrMarshal) || errors.Is(err, ErrInvalidConfig) || errors.Is(err, ErrMissingCredentials) ||
+		errors.Is(err, ErrSigning)
+}
+
+// Send hands event to cfg's transport, retrying transient failures with
+// capped exponential backoff and jitter until cfg's retry deadline or ctx's
+// deadline passes, whichever is first.
+// Every attempt goes through the circuit breaker for cfg's server, and
+// ErrBreakerOpen is returned without contacting the transport while it is
+// open. The outcome is counted in Stats.
+func Send(ctx context.Context, cfg Config, event Event) error {
+	_, err := sendResult(ctx, cfg, event)
+	return err
+}
+
+func sendResult(ctx context.Context, cfg Config, event Event) (Result, error) {
+	result, err := send(ctx, cfg, event)
+	statsFor(cfg).result(err)
+	return result, err
+}
+
+func send(ctx context.Context, cfg Config, event Event) (Result, error) {
+	cfg = cfg.normalized()
+	transport := cfg.GetTransport()
+	breaker := breakerFor(cfg)
+
+	attempt := func(ctx context.Context) (Result, error) {
+		if breaker != nil && !breaker.Allow() {
+			return Result{}, ErrBreakerOpen
+		}
+
+		result := Result{ClientID: event.ID}
+		var err error
+		if rt, ok := transport.(ResultTransport); ok {
+			result, err = rt.SendResult(ctx, event)
+		} else {
+			err = transport.Send(ctx, event)
+		}
+
+		if breaker != nil {
+			breaker.Record(err)
+		}
+		return result, err
+	}
+
+	if cfg.RetryDeadline < 0 {
+		return attempt(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.RetryDeadline)
+	defer cancel()
+
+	for n := 0; ; n++ {
+		result, err := attempt(ctx)
+		if !Retryable(err) {
+			return result, err
+		}
+
+		wait := backoff(n, cfg.RetryInitialBackoff, cfg.RetryMaxBackoff)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > wait {
+			wait = statusErr.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return result, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+		statsFor(cfg).retry()
+	}
+}
+
+// Deliver sends a report like Send. If delivery fails for any reason other
+// than a permanent rejection, including an open circuit breaker, the report
+// is diverted: it is handed to cfg.Fallback if set, and written to the spool
+// for later replay if cfg.SpoolDir is set. Deliver returns nil once the
+// report has been spooled.
+func Deliver(ctx context.Context, cfg Config, event Event) error {
+	_, err := deliver(ctx, cfg, event)
+	return err
+}
+
+func deliver(ctx context.Context, cfg Config, event Event) (Result, error) {
+	result, err := sendResult(ctx, cfg, event)
+
+	if err != nil && !permanent(err) && cfg.Fallback != nil {
+		if fallbackErr := cfg.Fallback.Send(ctx, event); fallbackErr != nil {
+			err = errors.Join(err, fallbackErr)
+		}
+	}
+
+	if err == nil {
+		if spool := openedSpool(cfg); spool != nil && spool.Size() > 0 {
+			spool.Kick()
+		}
+		return result, nil
+	}
+	if permanent(err) {
+		return result, err
+	}
+
+	spool, spoolErr := spoolFor(cfg)
+	if spoolErr != nil {
+		return result, errors.Join(err, spoolErr)
+	}
+	if spool == nil {
+		return result, err
+	}
+	if spoolErr := spool.append(cfg, event); spoolErr != nil {
+		return result, errors.Join(err, spoolErr)
+	}
+	statsFor(cfg).spool()
+
+	return result, nil
+}
+
+// ReportContext delivers event synchronously and returns what the server
+// said about it. It is for reports whose ID is needed straight away, so
+// sampling, rate limits and deduplication are skipped. With several
+// destinations, the first whose level filter event passes is sent to
+// synchronously and the result is its answer; the rest are queued as by
+// Submit. A report that was spooled returns a Result with only ClientID set.
+func ReportContext(ctx context.Context, cfg Config, event Event) (Result, error) {
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.Fingerprint == "" {
+		event.Fingerprint = Fingerprint(event.Level, event.Message, event.Stack)
+	}
+	if event.Context == (EventContext{}) {
+		event.Context = cfg.eventContext()
+	}
+
+	result := Result{ClientID: event.ID}
+	var errs []error
+	primary := true
+	for _, target := range cfg.destinations() {
+		if !target.accepts(event.Level) {
+			continue
+		}
+		if err := target.CheckCredentials(); err != nil {
+			errs = append(errs, target.wrapError(err))
+			continue
+		}
+
+		if primary {
+			primary = false
+			sent, err := deliver(ctx, target, event)
+			if err != nil {
+				errs = append(errs, target.wrapError(err))
+				continue
+			}
+			sent.ClientID = event.ID
+			result = sent
+			continue
+		}
+
+		errs = append(errs, enqueueDelivery(target, event))
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// ErrDropped is returned by Submit when an event was deliberately not
+// queued: it was sampled out, rate limited, held back as a repeat, or
+// matched no destination. It does not indicate a failure.
+var ErrDropped = errors.New("bugfixes: event dropped")
+
+// Submit fingerprints event, applies cfg's sample rates and rate limits,
+// holds it back if it repeats one already sent within cfg.DedupWindow, and
+// queues it for delivery to every destination whose level filter it passes.
+// Delivery failures go to ReportInternalError; the returned error only
+// reports whether the event could be queued, and is ErrDropped if it was
+// not queued anywhere by design.
+func Submit(cfg Config, event Event) error {
+	if event.Fingerprint == "" {
+		event.Fingerprint = Fingerprint(event.Level, event.Message, event.Stack)
+	}
+	if event.Context == (EventContext{}) {
+		event.Context = cfg.eventContext()
+	}
+	client := cfg.owner()
+	if !client.sampler.admit(&event, cfg) {
+		return ErrDropped
+	}
+	if cfg.DedupWindow > 0 && !client.deduper.admit(&event, cfg) {
+		statsFor(cfg).drop(DropDuplicate, 1)
+		return ErrDropped
+	}
+
+	return fanOut(cfg, event)
+}
+
+// fanOut queues event for delivery to every destination in cfg whose level
+// filter it passes, returning ErrDropped if there were none.
+func fanOut(cfg Config, event Event) error {
+	var errs []error
+	queued := false
+	for _, target := range cfg.destinations() {
+		if !target.accepts(event.Level) {
+			continue
+		}
+		if err := target.CheckCredentials(); err != nil {
+			ReportInternalError(cfg, target.wrapError(err))
+			continue
+		}
+
+		err := enqueueDelivery(target, event)
+		queued = queued || err == nil
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil || queued {
+		return err
+	}
+
+	return ErrDropped
+}
+
+// enqueueDelivery queues event for delivery with target, reporting failures
+// through ReportInternalError.
+func enqueueDelivery(target Config, event Event) error {
+	return queueFor(target).Enqueue(func(ctx context.Context) {
+		if err := Deliver(ctx, target, event); err != nil {
+			ReportInternalError(target, target.wrapError(fmt.Errorf("%w (%s): %w", ErrTransport, event.Kind, err)))
+		}
+	})
+}
+
+// backoff returns a full-jitter delay for the given attempt: a random
+// duration up to initial*2^attempt, capped at limit.
+func backoff(attempt int, initial, limit time.Duration) time.Duration {
+	ceiling := limit
+	if attempt < 32 {
+		if d := initial << attempt; d > 0 && d < limit {
+			ceiling = d
+		}
+	}
+
+	return rand.N(ceiling) + 1
+}
+
+// parseRetryAfter understands both forms of the header: delay-seconds and an
+// HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
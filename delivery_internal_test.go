@@ -0,0 +1,38 @@
+package bugfixes
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		value  string
+		expect time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{"-3", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+
+	for _, test := range tests {
+		if got := parseRetryAfter(test.value, now); got != test.expect {
+			t.Errorf("parseRetryAfter(%q) = %v, expected %v", test.value, got, test.expect)
+		}
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	for attempt := range 40 {
+		d := backoff(attempt, 100*time.Millisecond, time.Second)
+		if d <= 0 || d > time.Second {
+			t.Fatalf("attempt %d: backoff %v outside (0, 1s]", attempt, d)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package bugfixes_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func retryConfig(server string) bugfixes.Config {
+	return bugfixes.Config{
+		Server:              server,
+		AgentKey:            "key",
+		AgentSecret:         "secret",
+		RetryDeadline:       2 * time.Second,
+		RetryInitialBackoff: time.Millisecond,
+		RetryMaxBackoff:     5 * time.Millisecond,
+	}
+}
+
+func statusSequence(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		if n >= len(statuses) {
+			n = len(statuses) - 1
+		}
+		w.WriteHeader(statuses[n])
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func TestSendRetriesTransientFailures(t *testing.T) {
+	server, calls := statusSequence(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusOK)
+
+	err := bugfixes.Send(context.Background(), retryConfig(server.URL), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`)))
+	if err != nil {
+		t.Fatalf("expected eventual success, got %v", err)
+	}
+	if calls.Load() != 4 {
+		t.Fatalf("expected 4 attempts, got %d", calls.Load())
+	}
+}
+
+func TestSendDoesNotRetryPermanentFailures(t *testing.T) {
+	server, calls := statusSequence(t, http.StatusBadRequest)
+
+	err := bugfixes.Send(context.Background(), retryConfig(server.URL), bugfixes.NewEvent(bugfixes.KindBug, []byte(`{}`)))
+
+	var statusErr *bugfixes.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 status error, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls.Load())
+	}
+}
+
+func TestSendGivesUpWhenRetryAfterExceedsDeadline(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	start := time.Now()
+	err := bugfixes.Send(context.Background(), retryConfig(server.URL), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`)))
+
+	var statusErr *bugfixes.StatusError
+	if !errors.As(err, &statusErr) || statusErr.RetryAfter != 120*time.Second {
+		t.Fatalf("expected 503 with Retry-After, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls.Load())
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("expected Send to give up without waiting for Retry-After")
+	}
+}
+
+func TestSendStopsAtRetryDeadline(t *testing.T) {
+	server, calls := statusSequence(t, http.StatusBadGateway)
+
+	cfg := retryConfig(server.URL)
+	cfg.RetryDeadline = 50 * time.Millisecond
+
+	if err := bugfixes.Send(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))); err == nil {
+		t.Fatal("expected an error once the deadline passed")
+	}
+	if calls.Load() < 2 {
+		t.Fatalf("expected several attempts before the deadline, got %d", calls.Load())
+	}
+}
+
+func TestSendWithoutRetries(t *testing.T) {
+	server, calls := statusSequence(t, http.StatusServiceUnavailable, http.StatusOK)
+
+	cfg := retryConfig(server.URL)
+	cfg.RetryDeadline = -1
+
+	if err := bugfixes.Send(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))); err == nil {
+		t.Fatal("expected the 503 to be returned")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls.Load())
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	encodeErr := bugfixes.NewWriterTransport(io.Discard).Send(context.Background(), bugfixes.Event{Payload: []byte("{")})
+	if !errors.Is(encodeErr, bugfixes.ErrMarshal) {
+		t.Fatalf("expected an unencodable event to be ErrMarshal, got %v", encodeErr)
+	}
+
+	tests := []struct {
+		err    error
+		expect bool
+	}{
+		{nil, false},
+		{context.Canceled, false},
+		{errors.New("connection reset by peer"), true},
+		{&bugfixes.StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{&bugfixes.StatusError{StatusCode: http.StatusBadGateway}, true},
+		{&bugfixes.StatusError{StatusCode: http.StatusUnauthorized}, false},
+		{&bugfixes.StatusError{StatusCode: http.StatusNotFound}, false},
+		{fmt.Errorf("%w: encode tags: unsupported value", bugfixes.ErrMarshal), false},
+		{fmt.Errorf("%w: build request: invalid URL", bugfixes.ErrInvalidConfig), false},
+		{encodeErr, false},
+		{fmt.Errorf("%w: generate nonce: entropy unavailable", bugfixes.ErrSigning), false},
+	}
+
+	for _, test := range tests {
+		if got := bugfixes.Retryable(test.err); got != test.expect {
+			t.Errorf("Retryable(%v) = %v, expected %v", test.err, got, test.expect)
+		}
+	}
+}
+
+func TestSendDoesNotRetryMalformedEndpoint(t *testing.T) {
+	cfg := retryConfig("http://bad host")
+
+	start := time.Now()
+	err := bugfixes.Send(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`)))
+	if !errors.Is(err, bugfixes.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("expected a malformed endpoint to fail without retrying")
+	}
+}
+
+func TestSendRetriesAHungAttempt(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			<-release
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	cfg := retryConfig(server.URL)
+	cfg.Transport = &bugfixes.HTTPTransport{
+		Server:      server.URL,
+		AgentKey:    "key",
+		AgentSecret: "secret",
+		Client:      &http.Client{},
+		Timeout:     50 * time.Millisecond,
+	}
+
+	if err := bugfixes.Send(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))); err != nil {
+		t.Fatalf("expected the retry after the hung attempt to succeed, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected a second attempt, got %d", calls.Load())
+	}
+}
@@ -0,0 +1,93 @@
+package bugfixes
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Destination is one place reports are fanned out to. Each destination has
+// its own credentials, queue, circuit breaker and spool, so a slow or failing
+// destination never holds up the others. Retry, queue and breaker settings
+// are taken from the surrounding Config.
+type Destination struct {
+	// Name identifies the destination in errors and breaker states. It
+	// defaults to Server.
+	Name string
+
+	Server      string
+	AgentKey    string
+	AgentSecret string
+
+	// Transport replaces the HTTP transport, e.g. with a FileTransport to
+	// mirror reports to a local JSONL file.
+	Transport Transport
+
+	// MinLevel is the lowest level sent here. Empty sends every level.
+	MinLevel string
+
+	SignRequests      bool
+	Compress          bool
+	CompressThreshold int
+	SpoolDir          string
+}
+
+// destinations returns one config per place c sends reports: c itself, or a
+// config per entry in c.Destinations.
+func (c Config) destinations() []Config {
+	if len(c.Destinations) == 0 {
+		return []Config{c}
+	}
+
+	configs := make([]Config, 0, len(c.Destinations))
+	for i, d := range c.Destinations {
+		cfg := c
+		cfg.Destinations = nil
+		cfg.Fallback = nil
+
+		cfg.Server = d.Server
+		cfg.AgentKey = d.AgentKey
+		cfg.AgentSecret = d.AgentSecret
+		cfg.Transport = d.Transport
+		cfg.SignRequests = d.SignRequests
+		cfg.Compress = d.Compress
+		cfg.CompressThreshold = d.CompressThreshold
+		cfg.SpoolDir = d.SpoolDir
+
+		cfg.destination = d.Name
+		if cfg.destination == "" {
+			cfg.destination = d.Server
+		}
+		if cfg.destination == "" {
+			cfg.destination = "destination-" + strconv.Itoa(i)
+		}
+		cfg.minLevel = d.MinLevel
+
+		configs = append(configs, cfg)
+	}
+
+	return configs
+}
+
+// accepts reports whether events at level should be sent with c.
+func (c Config) accepts(level string) bool {
+	return c.minLevel == "" || ConvertLevelFromString(level) >= ConvertLevelFromString(c.minLevel)
+}
+
+// wrapError names c's destination in err, if c has one.
+func (c Config) wrapError(err error) error {
+	if c.destination == "" {
+		return err
+	}
+	return fmt.Errorf("destination %s: %w", c.destination, err)
+}
+
+// queueFor returns the queue for cfg's destination on the client cfg reports
+// through, starting it on first use. A config without destinations uses the
+// client's main queue.
+func queueFor(cfg Config) *Queue {
+	if cfg.destination == "" {
+		return cfg.owner().Queue()
+	}
+
+	return cfg.owner().destinationQueue(cfg)
+}
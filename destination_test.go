@@ -0,0 +1,117 @@
+package bugfixes_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func submitLevel(t *testing.T, cfg bugfixes.Config, level string) {
+	t.Helper()
+
+	event := bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))
+	event.Level = level
+	event.Message = uniqueKey(t)
+	if err := bugfixes.Submit(cfg, event); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+}
+
+func TestSubmitFansOutByLevel(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	everything := bugfixes.NewRecorder()
+	errorsOnly := bugfixes.NewRecorder()
+	cfg := bugfixes.Config{
+		Destinations: []bugfixes.Destination{
+			{Name: uniqueKey(t) + "-all", Transport: everything},
+			{Name: uniqueKey(t) + "-errors", Transport: errorsOnly, MinLevel: bugfixes.ERROR},
+		},
+	}
+
+	submitLevel(t, cfg, bugfixes.INFO)
+	submitLevel(t, cfg, bugfixes.ERROR)
+	submitLevel(t, cfg, bugfixes.PANIC)
+	if err := bugfixes.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	if got := len(everything.Events()); got != 3 {
+		t.Fatalf("expected every event at the unfiltered destination, got %d", got)
+	}
+	if got := len(errorsOnly.Events()); got != 2 {
+		t.Fatalf("expected errors and panics at the filtered destination, got %d", got)
+	}
+}
+
+func TestSlowDestinationDoesNotBlockOthers(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	release := make(chan struct{})
+	slow := bugfixes.TransportFunc(func(ctx context.Context, _ bugfixes.Event) error {
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	fast := bugfixes.NewRecorder()
+	cfg := bugfixes.Config{
+		QueueWorkers: 1,
+		Destinations: []bugfixes.Destination{
+			{Name: uniqueKey(t) + "-slow", Transport: slow},
+			{Name: uniqueKey(t) + "-fast", Transport: fast},
+		},
+	}
+
+	for range 3 {
+		submitLevel(t, cfg, bugfixes.ERROR)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(fast.Events()) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the fast destination to receive every event, got %d", len(fast.Events()))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	close(release)
+	if err := bugfixes.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+}
+
+func TestSubmitSkipsDestinationsWithoutCredentials(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	var reported []error
+	mirror := bugfixes.NewRecorder()
+	cfg := bugfixes.Config{
+		OnInternalError: func(err error) { reported = append(reported, err) },
+		Destinations: []bugfixes.Destination{
+			{Name: uniqueKey(t) + "-hosted", Server: "https://bugfixes.invalid"},
+			{Name: uniqueKey(t) + "-mirror", Transport: mirror},
+		},
+	}
+
+	if err := cfg.CheckCredentials(); err != nil {
+		t.Fatalf("expected one usable destination to be enough, got %v", err)
+	}
+
+	submitLevel(t, cfg, bugfixes.ERROR)
+	if err := bugfixes.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	if len(mirror.Events()) != 1 {
+		t.Fatalf("expected the mirror to receive the event, got %d", len(mirror.Events()))
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], bugfixes.ErrMissingCredentials) {
+		t.Fatalf("expected the hosted destination to be reported, got %v", reported)
+	}
+}
@@ -0,0 +1,121 @@
+package bugfixes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sentinel errors passed to Config.OnInternalError. Test for them with
+// errors.Is; the wrapped error carries the detail.
+var (
+	ErrMissingCredentials = errors.New("bugfixes: agent key and secret are not set")
+	ErrMarshal            = errors.New("bugfixes: failed to encode report")
+	ErrTransport          = errors.New("bugfixes: failed to send report")
+	ErrStatus             = errors.New("bugfixes: unexpected response status")
+	ErrInvalidConfig      = errors.New("bugfixes: invalid configuration")
+)
+
+// internalErrorInterval is how often the default handler prints the same
+// message.
+const internalErrorInterval = time.Minute
+
+// CheckCredentials returns an ErrMissingCredentials error naming the missing
+// variables if c cannot authenticate to the API. A config with a custom
+// Transport needs no credentials, and one with destinations needs at least
+// one usable destination.
+func (c Config) CheckCredentials() error {
+	if c.Transport != nil {
+		return nil
+	}
+	if len(c.Destinations) > 0 {
+		var errs []error
+		for _, target := range c.destinations() {
+			err := target.CheckCredentials()
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, target.wrapError(err))
+		}
+		return errors.Join(errs...)
+	}
+
+	var missing []string
+	if c.AgentKey == "" {
+		missing = append(missing, "BUGFIXES_AGENT_KEY")
+	}
+	if c.AgentSecret == "" {
+		missing = append(missing, "BUGFIXES_AGENT_SECRET")
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: create an agent and set %s", ErrMissingCredentials, strings.Join(missing, " and "))
+}
+
+// ReportInternalError hands a failure of the library itself to
+// cfg.OnInternalError. Without a handler, it is printed to stderr, at most
+// once a minute for the same message.
+func ReportInternalError(cfg Config, err error) {
+	if err == nil {
+		return
+	}
+	if cfg.OnInternalError != nil {
+		cfg.OnInternalError(err)
+		return
+	}
+
+	defaultErrorPrinter.print(err)
+}
+
+type errorPrinter struct {
+	mu   sync.Mutex
+	seen map[string]*printedError
+}
+
+type printedError struct {
+	at         time.Time
+	suppressed int
+}
+
+var defaultErrorPrinter = &errorPrinter{}
+
+func (p *errorPrinter) print(err error) {
+	message := err.Error()
+	now := time.Now()
+
+	p.mu.Lock()
+	if p.seen == nil {
+		p.seen = make(map[string]*printedError)
+	}
+	entry, ok := p.seen[message]
+	if ok && now.Sub(entry.at) < internalErrorInterval {
+		entry.suppressed++
+		p.mu.Unlock()
+		return
+	}
+
+	suppressed := 0
+	if ok {
+		suppressed = entry.suppressed
+	}
+	if len(p.seen) > 256 {
+		for seen, e := range p.seen {
+			if now.Sub(e.at) >= internalErrorInterval {
+				delete(p.seen, seen)
+			}
+		}
+	}
+	p.seen[message] = &printedError{at: now}
+	p.mu.Unlock()
+
+	if suppressed > 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "%s (repeated %d times)\n", message, suppressed)
+		return
+	}
+	_, _ = fmt.Fprintln(os.Stderr, message)
+}
@@ -0,0 +1,84 @@
+package bugfixes_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func TestCheckCredentials(t *testing.T) {
+	err := bugfixes.Config{AgentKey: "key"}.CheckCredentials()
+	if !errors.Is(err, bugfixes.ErrMissingCredentials) {
+		t.Fatalf("expected ErrMissingCredentials, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "BUGFIXES_AGENT_SECRET") || strings.Contains(err.Error(), "BUGFIXES_AGENT_KEY") {
+		t.Fatalf("expected only the secret to be named, got %q", err)
+	}
+
+	if err := (bugfixes.Config{Transport: bugfixes.NewRecorder()}).CheckCredentials(); err != nil {
+		t.Fatalf("expected a custom transport to need no credentials, got %v", err)
+	}
+}
+
+func TestSubmitReportsDeliveryFailures(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	var mu sync.Mutex
+	var reported []error
+	cfg := bugfixes.Config{
+		Server:        breakerServer(t),
+		RetryDeadline: -1,
+		Transport: bugfixes.TransportFunc(func(context.Context, bugfixes.Event) error {
+			return &bugfixes.StatusError{StatusCode: http.StatusUnauthorized}
+		}),
+		OnInternalError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, err)
+		},
+	}
+
+	if err := bugfixes.Submit(cfg, bugfixes.NewEvent(bugfixes.KindBug, []byte(`{}`))); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if err := bugfixes.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 1 {
+		t.Fatalf("expected one internal error, got %v", reported)
+	}
+	if !errors.Is(reported[0], bugfixes.ErrTransport) || !errors.Is(reported[0], bugfixes.ErrStatus) {
+		t.Fatalf("expected a transport and status error, got %v", reported[0])
+	}
+}
+
+func TestReportInternalErrorRateLimitsStderr(t *testing.T) {
+	orig := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("create pipe: %v", err)
+	}
+	os.Stderr = w
+	defer func() { os.Stderr = orig }()
+
+	problem := errors.New("bugfixes: " + uniqueKey(t))
+	for range 3 {
+		bugfixes.ReportInternalError(bugfixes.Config{}, problem)
+	}
+
+	_ = w.Close()
+	out, _ := io.ReadAll(r)
+	if got := strings.Count(string(out), problem.Error()); got != 1 {
+		t.Fatalf("expected the message printed once, got %d times in %q", got, out)
+	}
+}
@@ -0,0 +1,230 @@
+package bugfixes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fingerprintFrames is how many in-app stack frames feed a fingerprint.
+const fingerprintFrames = 5
+
+// libraryPrefix marks frames inside this module, which never identify a bug.
+const libraryPrefix = "github.com/bugfixes/go-bugfixes"
+
+var (
+	hexPattern    = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+	numberPattern = regexp.MustCompile(`\d+`)
+)
+
+// Fingerprint returns a stable identifier for an event, derived from its
+// level, its message with addresses and numbers masked, and the top in-app
+// function names of a debug.Stack trace. Argument values, file offsets and
+// goroutine IDs do not affect it.
+func Fingerprint(level, message string, stack []byte) string {
+	h := sha256.New()
+	h.Write([]byte(level))
+	h.Write([]byte{0})
+	h.Write([]byte(normalizeMessage(message)))
+	for _, frame := range inAppFrames(stack, fingerprintFrames) {
+		h.Write([]byte{0})
+		h.Write([]byte(frame))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func normalizeMessage(message string) string {
+	message = hexPattern.ReplaceAllString(message, "0x?")
+	return numberPattern.ReplaceAllString(message, "?")
+}
+
+// inAppFrames returns up to limit function names from a debug.Stack trace,
+// skipping the standard library and this module.
+func inAppFrames(stack []byte, limit int) []string {
+	var frames []string
+	for _, line := range strings.Split(string(stack), "\n") {
+		if len(frames) == limit {
+			break
+		}
+		if line == "" || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "goroutine ") || strings.HasPrefix(line, "created by ") {
+			continue
+		}
+
+		function := line
+		if i := strings.LastIndex(function, "("); i > 0 && strings.HasSuffix(function, ")") {
+			function = function[:i]
+		}
+		if !inApp(function) {
+			continue
+		}
+		frames = append(frames, function)
+	}
+
+	return frames
+}
+
+// inApp treats a function as application code if its import path starts
+// with a domain, or it lives in package main, and it is outside this module
+// (other than its tests).
+func inApp(function string) bool {
+	if strings.HasPrefix(function, "main.") {
+		return true
+	}
+
+	first, _, _ := strings.Cut(function, "/")
+	if !strings.Contains(first, ".") {
+		return false
+	}
+	if rest, ok := strings.CutPrefix(function, libraryPrefix); ok {
+		pkg, _, _ := strings.Cut(rest, ".")
+		return strings.HasSuffix(pkg, "_test")
+	}
+
+	return true
+}
+
+// deduper counts repeats of a fingerprint within a window instead of letting
+// them through, and reports the count once the window ends.
+type deduper struct {
+	mu        sync.Mutex
+	seen      map[string]*dedupEntry
+	lastPrune time.Time
+}
+
+type dedupEntry struct {
+	sentAt     time.Time
+	firstSeen  time.Time
+	lastSeen   time.Time
+	suppressed int
+
+	// last is the latest repeat and cfg the config it was submitted with;
+	// the summary is sent as last.
+	last  Event
+	cfg   Config
+	timer *time.Timer
+}
+
+// admit reports whether event should be sent. Repeats within cfg's
+// DedupWindow are counted and dropped; when the window ends a summary
+// carrying the occurrence count and first/last-seen times is sent in their
+// place.
+func (d *deduper) admit(event *Event, cfg Config) bool {
+	window := cfg.DedupWindow
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.seen == nil {
+		d.seen = make(map[string]*dedupEntry)
+	}
+	if now.Sub(d.lastPrune) > window {
+		for fingerprint, entry := range d.seen {
+			if now.Sub(entry.sentAt) > window && entry.suppressed == 0 {
+				delete(d.seen, fingerprint)
+			}
+		}
+		d.lastPrune = now
+	}
+
+	entry, ok := d.seen[event.Fingerprint]
+	if !ok {
+		d.seen[event.Fingerprint] = &dedupEntry{sentAt: now}
+		event.Occurrences = 1
+		event.FirstSeen = now.UTC()
+		event.LastSeen = now.UTC()
+		return true
+	}
+
+	if now.Sub(entry.sentAt) < window {
+		if entry.suppressed == 0 {
+			entry.firstSeen = now
+			fingerprint := event.Fingerprint
+			entry.timer = time.AfterFunc(entry.sentAt.Add(window).Sub(now), func() {
+				d.expire(fingerprint, entry)
+			})
+		}
+		entry.suppressed++
+		entry.lastSeen = now
+		entry.last = *event
+		entry.cfg = cfg
+		return false
+	}
+
+	// The window ended before its summary went out; this event carries
+	// the count instead.
+	firstSeen := now
+	if entry.suppressed > 0 {
+		firstSeen = entry.firstSeen
+		entry.timer.Stop()
+	}
+	event.Occurrences = entry.suppressed + 1
+	event.FirstSeen = firstSeen.UTC()
+	event.LastSeen = now.UTC()
+
+	d.seen[event.Fingerprint] = &dedupEntry{sentAt: now}
+
+	return true
+}
+
+// expire sends the summary for entry once its window has ended.
+func (d *deduper) expire(fingerprint string, entry *dedupEntry) {
+	d.mu.Lock()
+	summary, cfg, ok := d.takeSummary(fingerprint, entry)
+	d.mu.Unlock()
+
+	if ok {
+		sendSummary(cfg, summary)
+	}
+}
+
+// flush sends the summaries of every fingerprint with repeats still
+// counted, without waiting for their windows to end.
+func (d *deduper) flush() {
+	type pending struct {
+		cfg     Config
+		summary Event
+	}
+
+	d.mu.Lock()
+	var summaries []pending
+	for fingerprint, entry := range d.seen {
+		if summary, cfg, ok := d.takeSummary(fingerprint, entry); ok {
+			summaries = append(summaries, pending{cfg: cfg, summary: summary})
+		}
+	}
+	d.mu.Unlock()
+
+	for _, p := range summaries {
+		sendSummary(p.cfg, p.summary)
+	}
+}
+
+// takeSummary removes entry, if it is still current and has repeats
+// counted, and returns the summary to send for it. d.mu must be held.
+func (d *deduper) takeSummary(fingerprint string, entry *dedupEntry) (Event, Config, bool) {
+	if d.seen[fingerprint] != entry || entry.suppressed == 0 {
+		return Event{}, Config{}, false
+	}
+	delete(d.seen, fingerprint)
+	entry.timer.Stop()
+
+	summary := entry.last
+	summary.ID = newEventID()
+	summary.Occurrences = entry.suppressed
+	summary.FirstSeen = entry.firstSeen.UTC()
+	summary.LastSeen = entry.lastSeen.UTC()
+
+	return summary, entry.cfg, true
+}
+
+func sendSummary(cfg Config, summary Event) {
+	if err := fanOut(cfg, summary); err != nil && !errors.Is(err, ErrDropped) {
+		ReportInternalError(cfg, err)
+	}
+}
@@ -0,0 +1,161 @@
+package bugfixes_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+const stackA = `goroutine 7 [running]:
+runtime/debug.Stack()
+	/usr/local/go/src/runtime/debug/stack.go:26 +0x5e
+github.com/bugfixes/go-bugfixes/middleware.(*System).SendToBugfixes(0xc000012345, {0x6d2f40, 0xc0000a2010})
+	/src/middleware/bugfixes.go:60 +0x45
+example.com/shop/handlers.(*Cart).Checkout(0xc0001b2000, 0x3)
+	/src/handlers/cart.go:88 +0x1f2
+net/http.HandlerFunc.ServeHTTP(0xc0000b6000, {0x7f1e20, 0xc0001c0000}, 0xc0001a4100)
+	/usr/local/go/src/net/http/server.go:2294 +0x29
+`
+
+const stackB = `goroutine 4012 [running]:
+runtime/debug.Stack()
+	/usr/local/go/src/runtime/debug/stack.go:26 +0x5e
+github.com/bugfixes/go-bugfixes/middleware.(*System).SendToBugfixes(0xc0099aa000, {0x6d2f40, 0xc0044b2010})
+	/src/middleware/bugfixes.go:60 +0x45
+example.com/shop/handlers.(*Cart).Checkout(0xc0077f1000, 0x9)
+	/src/handlers/cart.go:91 +0x1f9
+net/http.HandlerFunc.ServeHTTP(0xc0055e6000, {0x7f1e20, 0xc0066c0000}, 0xc0033a4100)
+	/usr/local/go/src/net/http/server.go:2294 +0x29
+`
+
+func TestFingerprintIgnoresAddressesAndGoroutines(t *testing.T) {
+	a := bugfixes.Fingerprint("panic", "index out of range [3] with length 3", []byte(stackA))
+	b := bugfixes.Fingerprint("panic", "index out of range [9] with length 2", []byte(stackB))
+	if a != b {
+		t.Fatalf("expected equal fingerprints, got %s and %s", a, b)
+	}
+}
+
+func TestFingerprintDistinguishesEvents(t *testing.T) {
+	base := bugfixes.Fingerprint("panic", "nil map", []byte(stackA))
+
+	other := "goroutine 1 [running]:\nexample.com/shop/handlers.(*Cart).Add(0xc0001b2000)\n\t/src/handlers/cart.go:20 +0x1\n"
+
+	for name, fingerprint := range map[string]string{
+		"level":   bugfixes.Fingerprint("error", "nil map", []byte(stackA)),
+		"message": bugfixes.Fingerprint("panic", "closed channel", []byte(stackA)),
+		"frames":  bugfixes.Fingerprint("panic", "nil map", []byte(other)),
+	} {
+		if fingerprint == base {
+			t.Errorf("expected a different %s to change the fingerprint", name)
+		}
+	}
+}
+
+func TestSubmitDeduplicatesWithinWindow(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	recorder := bugfixes.NewRecorder()
+	cfg := bugfixes.Config{
+		Transport:   recorder,
+		DedupWindow: 100 * time.Millisecond,
+	}
+
+	event := func() bugfixes.Event {
+		event := bugfixes.NewEvent(bugfixes.KindBug, []byte(`{"bug":"x"}`))
+		event.Level = "panic"
+		event.Message = t.Name()
+		event.Stack = []byte(stackA)
+		return event
+	}
+
+	if err := bugfixes.Submit(cfg, event()); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	for range 2 {
+		if err := bugfixes.Submit(cfg, event()); !errors.Is(err, bugfixes.ErrDropped) {
+			t.Fatalf("expected a repeat to be reported as dropped, got %v", err)
+		}
+	}
+	time.Sleep(150 * time.Millisecond)
+	if err := bugfixes.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	events := recorder.Events()
+	if len(events) != 2 {
+		t.Fatalf("expected the first report and one summary when the window ended, got %d", len(events))
+	}
+	if events[0].Occurrences != 1 || events[0].Fingerprint == "" {
+		t.Fatalf("expected the first report to be fingerprinted, got %+v", events[0])
+	}
+
+	summary := events[1]
+	if summary.Occurrences != 2 {
+		t.Fatalf("expected the two suppressed repeats to be counted, got %d", summary.Occurrences)
+	}
+	if summary.ID == events[0].ID || summary.LastSeen.Before(summary.FirstSeen) {
+		t.Fatalf("expected a new report spanning the repeats, got %+v", summary)
+	}
+
+	if err := bugfixes.Submit(cfg, event()); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if err := bugfixes.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if events := recorder.Events(); len(events) != 3 || events[2].Occurrences != 1 {
+		t.Fatalf("expected the next report to start a new window, got %d events", len(events))
+	}
+}
+
+func TestCloseSendsHeldBackRepeats(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	client := bugfixes.NewClient(bugfixes.Config{Transport: recorder, DedupWindow: time.Hour})
+
+	level := uniqueKey(t)
+	for range 4 {
+		event := bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))
+		event.Level = level
+		event.Message = "burst"
+		if err := client.Submit(event); err != nil && !errors.Is(err, bugfixes.ErrDropped) {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+	if err := client.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	events := recorder.Events()
+	if len(events) != 2 || events[1].Occurrences != 3 {
+		t.Fatalf("expected the first report and a summary of three repeats, got %d events", len(events))
+	}
+}
+
+func TestEventBodyMergesMetadata(t *testing.T) {
+	event := bugfixes.NewEvent(bugfixes.KindBug, []byte(`{"bug":"x"}`))
+	event.Fingerprint = "abc"
+	event.Occurrences = 4
+	event.FirstSeen = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	event.LastSeen = event.FirstSeen.Add(time.Minute)
+
+	body, err := event.Body()
+	if err != nil {
+		t.Fatalf("body: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got["bug"] != "x" || got["fingerprint"] != "abc" || got["occurrences"] != float64(4) {
+		t.Fatalf("unexpected body %s", body)
+	}
+	if got["first_seen"] != "2026-01-01T00:00:00Z" {
+		t.Fatalf("expected first_seen in the body, got %v", got["first_seen"])
+	}
+}
@@ -0,0 +1,59 @@
+package bugfixes
+
+import "strconv"
+
+// Level names, as used in Config.LogLevel and Event.Level.
+const (
+	LOG   = "log"
+	DEBUG = "debug"
+
+	INFO = "info"
+	WARN = "warn"
+
+	ERROR = "error"
+
+	CRASH = "crash"
+	PANIC = "panic"
+	FATAL = "fatal"
+
+	UNKNOWN = "unknown"
+)
+
+const (
+	LevelDebug   = 1
+	LevelLog     = 2
+	LevelInfo    = 3
+	LevelWarn    = 4
+	LevelError   = 5
+	LevelCrash   = 6
+	LevelUnknown = 9
+)
+
+// ConvertLevelFromString converts a level name to its numeric value.
+func ConvertLevelFromString(s string) int {
+	switch s {
+	case LOG:
+		return LevelLog
+	case DEBUG:
+		return LevelDebug
+	case INFO:
+		return LevelInfo
+	case WARN:
+		return LevelWarn
+	case ERROR:
+		return LevelError
+	case CRASH, PANIC, FATAL:
+		return LevelCrash
+	case UNKNOWN:
+		return LevelUnknown
+	default:
+		lvl, err := strconv.Atoi(s)
+		if err != nil {
+			return LevelUnknown
+		}
+		if lvl >= LevelUnknown {
+			return LevelUnknown
+		}
+		return lvl
+	}
+}
@@ -0,0 +1,200 @@
+package bugfixes
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// LevelRules overrides Config.LogLevel for particular packages or files.
+// Parse them with ParseLevelRules; the zero value and nil have no rules.
+// Results are cached per call site, so matching is cheap on the hot path.
+type LevelRules struct {
+	rules []levelRule
+	cache sync.Map
+}
+
+type levelRule struct {
+	pattern string
+	level   string
+}
+
+type levelMatch struct {
+	level string
+	ok    bool
+}
+
+// ParseLevelRules reads a comma-separated list of pattern=level pairs, such
+// as "github.com/acme/billing/...=debug,*=warn". A pattern is one of:
+//
+//   - "*", matching everything
+//   - a package path ending in "/...", matching the package and those below it
+//   - a file glob such as "*_handler.go" or "internal/db/*.go", matched
+//     against the trailing elements of the caller's file path
+//   - a package path, matching that package only
+//
+// The longest matching pattern wins.
+func ParseLevelRules(value string) (*LevelRules, error) {
+	rules := &LevelRules{}
+	for pair := range strings.SplitSeq(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		pattern, level, ok := strings.Cut(pair, "=")
+		pattern = strings.TrimSpace(pattern)
+		level = strings.ToLower(strings.TrimSpace(level))
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("%w: log level rule %q is not pattern=level", ErrInvalidConfig, pair)
+		}
+		if err := validateLevel("log level rule "+pattern, level); err != nil {
+			return nil, err
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: log level rule %q: %w", ErrInvalidConfig, pair, err)
+		}
+		rules.rules = append(rules.rules, levelRule{pattern: pattern, level: level})
+	}
+
+	return rules, nil
+}
+
+// UnmarshalText parses rules in the ParseLevelRules format, so they can be
+// set from a configuration file.
+func (r *LevelRules) UnmarshalText(text []byte) error {
+	parsed, err := ParseLevelRules(string(text))
+	if err != nil {
+		return err
+	}
+	r.rules = parsed.rules
+	r.cache.Clear()
+
+	return nil
+}
+
+// String returns the rules in the ParseLevelRules format.
+func (r *LevelRules) String() string {
+	if r == nil {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(r.rules))
+	for _, rule := range r.rules {
+		pairs = append(pairs, rule.pattern+"="+rule.level)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// Level returns the level of the rule matching a caller in package pkg and
+// file, and whether any rule matched.
+func (r *LevelRules) Level(pkg, file string) (string, bool) {
+	if r == nil || len(r.rules) == 0 {
+		return "", false
+	}
+
+	key := pkg + "\x00" + file
+	if cached, ok := r.cache.Load(key); ok {
+		match := cached.(levelMatch)
+		return match.level, match.ok
+	}
+
+	var match levelMatch
+	longest := -1
+	for _, rule := range r.rules {
+		if len(rule.pattern) > longest && rule.matches(pkg, file) {
+			match = levelMatch{level: rule.level, ok: true}
+			longest = len(rule.pattern)
+		}
+	}
+	r.cache.Store(key, match)
+
+	return match.level, match.ok
+}
+
+func (rule levelRule) matches(pkg, file string) bool {
+	pattern := rule.pattern
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasSuffix(pattern, "/..."):
+		prefix := strings.TrimSuffix(pattern, "/...")
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+	case strings.ContainsAny(pattern, "*?[") || strings.HasSuffix(pattern, ".go"):
+		ok, _ := path.Match(pattern, trailingElements(file, strings.Count(pattern, "/")+1))
+		return ok
+	default:
+		return pkg == pattern
+	}
+}
+
+// trailingElements returns the last n slash-separated elements of file.
+func trailingElements(file string, n int) string {
+	file = strings.ReplaceAll(file, "\\", "/")
+	i := len(file)
+	for ; n > 0 && i > 0; n-- {
+		i = strings.LastIndex(file[:i], "/")
+		if i < 0 {
+			return file
+		}
+	}
+
+	return file[i+1:]
+}
+
+// LevelFor returns the reporting level for a caller in package pkg and file:
+// the matching LogLevels rule, or LogLevel.
+func (c Config) LevelFor(pkg, file string) string {
+	if level, ok := c.LogLevels.Level(pkg, file); ok {
+		return level
+	}
+
+	return c.LogLevel
+}
+
+// PackageOf returns the package path of a function name as reported by
+// runtime.Frame, e.g. "github.com/acme/billing" for
+// "github.com/acme/billing.(*Service).Charge".
+func PackageOf(function string) string {
+	slash := strings.LastIndex(function, "/")
+	dot := strings.Index(function[slash+1:], ".")
+	if dot < 0 {
+		return function
+	}
+
+	return function[:slash+1+dot]
+}
+
+// envLevelRulesCache holds the rules last parsed from the environment, so
+// they are parsed again, and their per-call-site cache rebuilt, only when
+// the variable changes.
+var envLevelRulesCache struct {
+	sync.Mutex
+	name, raw string
+	rules     *LevelRules
+}
+
+func envLevelRules(name string) *LevelRules {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return nil
+	}
+
+	envLevelRulesCache.Lock()
+	defer envLevelRulesCache.Unlock()
+	if envLevelRulesCache.name == name && envLevelRulesCache.raw == raw {
+		return envLevelRulesCache.rules
+	}
+
+	rules, err := ParseLevelRules(raw)
+	if err != nil {
+		ReportInternalError(Config{}, fmt.Errorf("%s: %w, rules ignored", name, err))
+		rules = nil
+	}
+	envLevelRulesCache.name, envLevelRulesCache.raw, envLevelRulesCache.rules = name, raw, rules
+
+	return rules
+}
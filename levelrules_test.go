@@ -0,0 +1,86 @@
+package bugfixes_test
+
+import (
+	"errors"
+	"testing"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func TestLevelRules(t *testing.T) {
+	rules, err := bugfixes.ParseLevelRules("github.com/acme/billing/...=debug, *_handler.go=info, internal/db/*.go=error, github.com/acme/shop=log, *=warn")
+	if err != nil {
+		t.Fatalf("parse rules: %v", err)
+	}
+
+	tests := []struct {
+		pkg, file, want string
+	}{
+		{"github.com/acme/billing", "/src/billing/charge.go", bugfixes.DEBUG},
+		{"github.com/acme/billing/invoice", "/src/billing/invoice/pdf.go", bugfixes.DEBUG},
+		{"github.com/acme/billingx", "/src/billingx/main.go", bugfixes.WARN},
+		{"github.com/acme/api", "/src/api/user_handler.go", bugfixes.INFO},
+		{"github.com/acme/internal/db", "/src/internal/db/conn.go", bugfixes.ERROR},
+		{"github.com/acme/shop", "/src/shop/cart.go", bugfixes.LOG},
+		{"github.com/acme/shop/cart", "/src/shop/cart/cart.go", bugfixes.WARN},
+	}
+	for _, tt := range tests {
+		for range 2 {
+			if got, _ := rules.Level(tt.pkg, tt.file); got != tt.want {
+				t.Fatalf("expected %s for %s in %s, got %s", tt.want, tt.pkg, tt.file, got)
+			}
+		}
+	}
+
+	cfg := bugfixes.Config{LogLevel: bugfixes.ERROR}
+	if got := cfg.LevelFor("github.com/acme/billing", "charge.go"); got != bugfixes.ERROR {
+		t.Fatalf("expected LogLevel without rules, got %s", got)
+	}
+	cfg.LogLevels = rules
+	if got := cfg.LevelFor("github.com/acme/billing", "charge.go"); got != bugfixes.DEBUG {
+		t.Fatalf("expected the rule level, got %s", got)
+	}
+}
+
+func TestParseLevelRulesRejectsInvalid(t *testing.T) {
+	for _, value := range []string{"billing", "billing=loud", "=debug", "[=debug"} {
+		if _, err := bugfixes.ParseLevelRules(value); !errors.Is(err, bugfixes.ErrInvalidConfig) {
+			t.Fatalf("expected ErrInvalidConfig for %q, got %v", value, err)
+		}
+	}
+}
+
+func TestPackageOf(t *testing.T) {
+	tests := map[string]string{
+		"github.com/acme/billing.(*Service).Charge": "github.com/acme/billing",
+		"github.com/acme/billing.Charge.func1":      "github.com/acme/billing",
+		"main.main":                                 "main",
+	}
+	for function, want := range tests {
+		if got := bugfixes.PackageOf(function); got != want {
+			t.Fatalf("expected %s for %s, got %s", want, function, got)
+		}
+	}
+}
+
+func TestLoadConfigReadsLevelRules(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfigFile(t, "bugfixes.yaml", "log_levels: github.com/acme/billing/...=debug\n")
+
+	cfg, _, err := bugfixes.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if got := cfg.LogLevels.String(); got != "github.com/acme/billing/...=debug" {
+		t.Fatalf("expected rules from the file, got %q", got)
+	}
+
+	t.Setenv("BUGFIXES_LOG_LEVELS", "*=warn")
+	cfg, sources, err := bugfixes.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if got := cfg.LogLevels.String(); got != "*=warn" || sources.Lookup("log_levels") != bugfixes.SourceEnv {
+		t.Fatalf("expected rules from the environment, got %q", got)
+	}
+}
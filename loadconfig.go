@@ -0,0 +1,244 @@
+package bugfixes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source names the configuration layer a value came from.
+type Source string
+
+// Configuration layers, lowest precedence first.
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceCode    Source = "code"
+)
+
+// Sources maps a setting, by its file key, to the layer that supplied it.
+type Sources map[string]Source
+
+// Lookup returns the layer that supplied key. Settings no layer set come
+// from the defaults.
+func (s Sources) Lookup(key string) Source {
+	if source, ok := s[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// Settings is one layer of configuration. Nil fields are unset and leave the
+// layer below alone, so unlike Merge a layer can turn a boolean off. The
+// yaml tags are the keys used in configuration files.
+type Settings struct {
+	Server      *string `yaml:"server"`
+	AgentKey    *string `yaml:"agent_key"`
+	AgentSecret *string `yaml:"agent_secret"`
+	LogLevel    *string `yaml:"log_level"`
+	LocalOnly   *bool   `yaml:"local_only"`
+
+	LogLevels *LevelRules `yaml:"log_levels"`
+
+	Service     *string `yaml:"service"`
+	Environment *string `yaml:"environment"`
+	Release     *string `yaml:"release"`
+
+	SignRequests      *bool `yaml:"sign_requests"`
+	Compress          *bool `yaml:"compress"`
+	CompressThreshold *int  `yaml:"compress_threshold"`
+
+	QueueSize    *int        `yaml:"queue_size"`
+	QueueWorkers *int        `yaml:"queue_workers"`
+	DropPolicy   *DropPolicy `yaml:"drop_policy"`
+
+	RetryDeadline       *time.Duration `yaml:"retry_deadline"`
+	RetryInitialBackoff *time.Duration `yaml:"retry_initial_backoff"`
+	RetryMaxBackoff     *time.Duration `yaml:"retry_max_backoff"`
+
+	SpoolDir            *string        `yaml:"spool_dir"`
+	SpoolMaxBytes       *int64         `yaml:"spool_max_bytes"`
+	SpoolMaxAge         *time.Duration `yaml:"spool_max_age"`
+	SpoolReplayInterval *time.Duration `yaml:"spool_replay_interval"`
+
+	BreakerThreshold *int           `yaml:"breaker_threshold"`
+	BreakerCooldown  *time.Duration `yaml:"breaker_cooldown"`
+
+	DedupWindow *time.Duration `yaml:"dedup_window"`
+
+	SampleRates          map[string]float64   `yaml:"sample_rates"`
+	LevelRateLimits      map[string]RateLimit `yaml:"level_rate_limits"`
+	FingerprintRateLimit *RateLimit           `yaml:"fingerprint_rate_limit"`
+
+	Strict *bool `yaml:"strict"`
+}
+
+// LoadConfig builds a Config from four layers, each overriding the one
+// before: the defaults, a YAML or JSON file, BUGFIXES_* environment variables,
+// and overrides. The file is path, or BUGFIXES_CONFIG if path is empty; with
+// neither, no file is read. The returned Sources records which layer
+// supplied each setting.
+func LoadConfig(path string, overrides ...Settings) (Config, Sources, error) {
+	return loadConfig(Config{}, path, overrides)
+}
+
+// loadConfig is LoadConfig with base in place of the zero defaults.
+func loadConfig(base Config, path string, overrides []Settings) (Config, Sources, error) {
+	sources := Sources{}
+	cfg := base
+
+	if path == "" {
+		path = os.Getenv("BUGFIXES_CONFIG")
+	}
+	if path != "" {
+		file, err := ReadSettings(path)
+		if err != nil {
+			return Config{}, nil, err
+		}
+		cfg = cfg.apply(file, SourceFile, sources)
+	}
+
+	cfg = cfg.apply(envSettings(), SourceEnv, sources)
+	for _, override := range overrides {
+		cfg = cfg.apply(override, SourceCode, sources)
+	}
+
+	return cfg.normalized(), sources, nil
+}
+
+// ReadSettings reads a settings layer from a YAML or JSON file. Unknown keys
+// are an error.
+func ReadSettings(path string) (Settings, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Settings{}, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var settings Settings
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&settings); err != nil && !errors.Is(err, io.EOF) {
+		return Settings{}, fmt.Errorf("%w: %s: %w", ErrInvalidConfig, path, err)
+	}
+
+	return settings, nil
+}
+
+// Apply returns c with every field set in s replaced.
+func (c Config) Apply(s Settings) Config {
+	return c.apply(s, SourceCode, nil)
+}
+
+func (c Config) apply(s Settings, source Source, sources Sources) Config {
+	set(&c.Server, s.Server, "server", source, sources)
+	set(&c.AgentKey, s.AgentKey, "agent_key", source, sources)
+	set(&c.AgentSecret, s.AgentSecret, "agent_secret", source, sources)
+	set(&c.LogLevel, s.LogLevel, "log_level", source, sources)
+	set(&c.LocalOnly, s.LocalOnly, "local_only", source, sources)
+	if s.LogLevels != nil {
+		set(&c.LogLevels, &s.LogLevels, "log_levels", source, sources)
+	}
+
+	set(&c.Service, s.Service, "service", source, sources)
+	set(&c.Environment, s.Environment, "environment", source, sources)
+	set(&c.Release, s.Release, "release", source, sources)
+
+	set(&c.SignRequests, s.SignRequests, "sign_requests", source, sources)
+	set(&c.Compress, s.Compress, "compress", source, sources)
+	set(&c.CompressThreshold, s.CompressThreshold, "compress_threshold", source, sources)
+
+	set(&c.QueueSize, s.QueueSize, "queue_size", source, sources)
+	set(&c.QueueWorkers, s.QueueWorkers, "queue_workers", source, sources)
+	set(&c.DropPolicy, s.DropPolicy, "drop_policy", source, sources)
+
+	set(&c.RetryDeadline, s.RetryDeadline, "retry_deadline", source, sources)
+	set(&c.RetryInitialBackoff, s.RetryInitialBackoff, "retry_initial_backoff", source, sources)
+	set(&c.RetryMaxBackoff, s.RetryMaxBackoff, "retry_max_backoff", source, sources)
+
+	set(&c.SpoolDir, s.SpoolDir, "spool_dir", source, sources)
+	set(&c.SpoolMaxBytes, s.SpoolMaxBytes, "spool_max_bytes", source, sources)
+	set(&c.SpoolMaxAge, s.SpoolMaxAge, "spool_max_age", source, sources)
+	set(&c.SpoolReplayInterval, s.SpoolReplayInterval, "spool_replay_interval", source, sources)
+
+	set(&c.BreakerThreshold, s.BreakerThreshold, "breaker_threshold", source, sources)
+	set(&c.BreakerCooldown, s.BreakerCooldown, "breaker_cooldown", source, sources)
+
+	set(&c.DedupWindow, s.DedupWindow, "dedup_window", source, sources)
+
+	if s.SampleRates != nil {
+		set(&c.SampleRates, &s.SampleRates, "sample_rates", source, sources)
+	}
+	if s.LevelRateLimits != nil {
+		set(&c.LevelRateLimits, &s.LevelRateLimits, "level_rate_limits", source, sources)
+	}
+	set(&c.FingerprintRateLimit, s.FingerprintRateLimit, "fingerprint_rate_limit", source, sources)
+
+	set(&c.Strict, s.Strict, "strict", source, sources)
+
+	return c
+}
+
+func set[T any](field *T, value *T, key string, source Source, sources Sources) {
+	if value == nil {
+		return
+	}
+	*field = *value
+	if sources != nil {
+		sources[key] = source
+	}
+}
+
+// envSettings reads the BUGFIXES_* environment variables. Empty variables
+// are unset; invalid ones are reported and ignored.
+func envSettings() Settings {
+	return Settings{
+		Server:      envString("BUGFIXES_SERVER"),
+		AgentKey:    envString("BUGFIXES_AGENT_KEY"),
+		AgentSecret: envString("BUGFIXES_AGENT_SECRET"),
+		LogLevel:    envString("BUGFIXES_LOG_LEVEL"),
+		LocalOnly:   envBool("BUGFIXES_LOCAL_ONLY"),
+		LogLevels:   envLevelRules("BUGFIXES_LOG_LEVELS"),
+		SpoolDir:    envString("BUGFIXES_SPOOL_DIR"),
+
+		Service:     envString("BUGFIXES_SERVICE"),
+		Environment: envString("BUGFIXES_ENVIRONMENT"),
+		Release:     envString("BUGFIXES_RELEASE"),
+
+		SignRequests: envBool("BUGFIXES_SIGN_REQUESTS"),
+		Compress:     envBool("BUGFIXES_COMPRESS"),
+		SampleRates:  envSampleRates("BUGFIXES_SAMPLE_RATES"),
+	}
+}
+
+func envString(name string) *string {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func envBool(name string) *bool {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		ReportInternalError(Config{}, fmt.Errorf("%w: %s value %q is not a boolean, ignoring it", ErrInvalidConfig, name, raw))
+		return nil
+	}
+
+	return &value
+}
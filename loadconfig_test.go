@@ -0,0 +1,171 @@
+package bugfixes_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+
+	for _, name := range []string{
+		"BUGFIXES_CONFIG",
+		"BUGFIXES_SERVER",
+		"BUGFIXES_AGENT_KEY",
+		"BUGFIXES_AGENT_SECRET",
+		"BUGFIXES_LOG_LEVEL",
+		"BUGFIXES_LOG_LEVELS",
+		"BUGFIXES_LOCAL_ONLY",
+		"BUGFIXES_SPOOL_DIR",
+		"BUGFIXES_SIGN_REQUESTS",
+		"BUGFIXES_COMPRESS",
+		"BUGFIXES_SAMPLE_RATES",
+		"BUGFIXES_SERVICE",
+		"BUGFIXES_ENVIRONMENT",
+		"BUGFIXES_RELEASE",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func writeConfigFile(t *testing.T, name, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+
+	return path
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfigFile(t, "bugfixes.yaml", `
+server: https://file.example
+agent_key: file-key
+agent_secret: file-secret
+local_only: true
+queue_size: 50
+drop_policy: oldest
+retry_deadline: 45s
+fingerprint_rate_limit:
+  per_second: 2
+  burst: 4
+`)
+	t.Setenv("BUGFIXES_AGENT_KEY", "env-key")
+
+	localOnly := false
+	cfg, sources, err := bugfixes.LoadConfig(path, bugfixes.Settings{LocalOnly: &localOnly})
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	if cfg.Server != "https://file.example" || cfg.AgentSecret != "file-secret" {
+		t.Fatalf("expected file values, got %q and %q", cfg.Server, cfg.AgentSecret)
+	}
+	if cfg.AgentKey != "env-key" {
+		t.Fatalf("expected the environment to override the file, got %q", cfg.AgentKey)
+	}
+	if cfg.LocalOnly {
+		t.Fatal("expected the code override to turn LocalOnly off")
+	}
+	if cfg.QueueSize != 50 || cfg.DropPolicy != bugfixes.DropOldest || cfg.RetryDeadline != 45*time.Second {
+		t.Fatalf("expected queue and retry settings from the file, got %d, %v, %v", cfg.QueueSize, cfg.DropPolicy, cfg.RetryDeadline)
+	}
+	if cfg.FingerprintRateLimit != (bugfixes.RateLimit{PerSecond: 2, Burst: 4}) {
+		t.Fatalf("expected the fingerprint rate limit from the file, got %+v", cfg.FingerprintRateLimit)
+	}
+	if cfg.RetryMaxBackoff != bugfixes.DefaultRetryMaxBackoff {
+		t.Fatalf("expected defaults for unset values, got %v", cfg.RetryMaxBackoff)
+	}
+
+	expected := map[string]bugfixes.Source{
+		"server":            bugfixes.SourceFile,
+		"agent_key":         bugfixes.SourceEnv,
+		"local_only":        bugfixes.SourceCode,
+		"retry_max_backoff": bugfixes.SourceDefault,
+	}
+	for key, source := range expected {
+		if got := sources.Lookup(key); got != source {
+			t.Fatalf("expected %s from %s, got %s", key, source, got)
+		}
+	}
+}
+
+func TestLoadConfigReadsJSONFromEnvironmentPath(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfigFile(t, "bugfixes.json", `{"server": "https://json.example", "sample_rates": {"info": 0.5}}`)
+	t.Setenv("BUGFIXES_CONFIG", path)
+
+	cfg, sources, err := bugfixes.LoadConfig("")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	if cfg.Server != "https://json.example" {
+		t.Fatalf("expected the server from the JSON file, got %q", cfg.Server)
+	}
+	if cfg.SampleRates["info"] != 0.5 {
+		t.Fatalf("expected sample rates from the JSON file, got %v", cfg.SampleRates)
+	}
+	if sources.Lookup("sample_rates") != bugfixes.SourceFile {
+		t.Fatalf("expected sample rates from the file, got %s", sources.Lookup("sample_rates"))
+	}
+}
+
+func TestLoadConfigWithoutFile(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("BUGFIXES_LOG_LEVEL", "warn")
+
+	cfg, sources, err := bugfixes.LoadConfig("")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	if cfg.Server != bugfixes.DefaultServer {
+		t.Fatalf("expected the default server, got %q", cfg.Server)
+	}
+	if cfg.LogLevel != "warn" || sources.Lookup("log_level") != bugfixes.SourceEnv {
+		t.Fatalf("expected the log level from the environment, got %q from %s", cfg.LogLevel, sources.Lookup("log_level"))
+	}
+}
+
+func TestLoadConfigRejectsBadFiles(t *testing.T) {
+	clearConfigEnv(t)
+
+	tests := map[string]string{
+		"unknown key":  "servre: https://typo.example\n",
+		"bad duration": "retry_deadline: soon\n",
+		"bad policy":   "drop_policy: random\n",
+	}
+	for name, contents := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := writeConfigFile(t, "bugfixes.yaml", contents)
+			if _, _, err := bugfixes.LoadConfig(path); !errors.Is(err, bugfixes.ErrInvalidConfig) {
+				t.Fatalf("expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+
+	if _, _, err := bugfixes.LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); !errors.Is(err, bugfixes.ErrInvalidConfig) {
+		t.Fatalf("expected a missing file to be ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestConfigApplyCanDisable(t *testing.T) {
+	off := false
+	cfg := bugfixes.Config{LocalOnly: true, Compress: true}.Apply(bugfixes.Settings{LocalOnly: &off})
+
+	if cfg.LocalOnly {
+		t.Fatal("expected Apply to turn LocalOnly off")
+	}
+	if !cfg.Compress {
+		t.Fatal("expected unset settings to be left alone")
+	}
+}
@@ -0,0 +1,98 @@
+package logs
+
+import (
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+const logsPackagePrefix = "github.com/bugfixes/go-bugfixes/logs."
+
+// maxCallerDepth bounds the stack walked to find the caller.
+const maxCallerDepth = 64
+
+var (
+	helperFuncs    sync.Map // function name -> struct{}
+	helperPackages sync.Map // package path -> struct{}
+)
+
+// Helper marks the calling function as a logging helper, as testing.TB's
+// Helper does: its frame is skipped when the file and line of an entry are
+// found, so a wrapper around this package reports its caller instead of
+// itself. It may be called any number of times.
+func Helper() {
+	var pcs [1]uintptr
+	if runtime.Callers(2, pcs[:]) == 0 {
+		return
+	}
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	helperFuncs.Store(frame.Function, struct{}{})
+}
+
+// HelperPackage marks every function in the package with import path pkg
+// as a logging helper. See Helper.
+func HelperPackage(pkg string) {
+	helperPackages.Store(pkg, struct{}{})
+}
+
+// isHelperFrame reports whether frame is a registered helper.
+func isHelperFrame(frame runtime.Frame) bool {
+	if _, ok := helperFuncs.Load(frame.Function); ok {
+		return true
+	}
+	_, ok := helperPackages.Load(bugfixes.PackageOf(frame.Function))
+
+	return ok
+}
+
+// findCaller sets the entry's file and line to the first frame outside this
+// package and the registered helpers, skipping SkipDepthOverride more, or
+// the outermost such frame if the stack is shorter. When the caller's
+// program counter is already known the walk starts there instead.
+func (b *BugFixes) findCaller() {
+	var pcs [maxCallerDepth]uintptr
+	stack := pcs[:runtime.Callers(2, pcs[:])]
+	inPackage := b.pc == 0
+	if b.pc != 0 {
+		if i := slices.Index(stack, b.pc); i >= 0 {
+			stack = stack[i:]
+		} else {
+			stack = []uintptr{b.pc}
+		}
+	}
+
+	skip := b.SkipDepthOverride
+	var caller runtime.Frame
+	frames := runtime.CallersFrames(stack)
+	for more := true; more; {
+		var frame runtime.Frame
+		frame, more = frames.Next()
+		if inPackage && strings.HasPrefix(frame.Function, logsPackagePrefix) {
+			continue
+		}
+		inPackage = false
+		if isHelperFrame(frame) {
+			continue
+		}
+		caller = frame
+		if skip <= 0 {
+			break
+		}
+		skip--
+	}
+
+	if caller.PC != 0 {
+		b.setCaller(caller)
+	}
+}
+
+func (b *BugFixes) setCaller(frame runtime.Frame) {
+	b.File = frame.File
+	b.pkg = bugfixes.PackageOf(frame.Function)
+	b.LineNumber = frame.Line
+	b.Line = strconv.Itoa(frame.Line)
+}
@@ -0,0 +1,91 @@
+package logs_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"runtime"
+	"testing"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+	"github.com/bugfixes/go-bugfixes/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reportedCaller struct {
+	File       string `json:"file"`
+	LineNumber int    `json:"line_number"`
+}
+
+func newCallerLogger(t *testing.T) (*logs.Logger, func() reportedCaller) {
+	t.Helper()
+
+	recorder := bugfixes.NewRecorder()
+	client := bugfixes.NewClient(bugfixes.Config{Transport: recorder, LogLevel: bugfixes.ERROR})
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	reported := func() reportedCaller {
+		t.Helper()
+		require.NoError(t, client.Flush(context.Background()))
+		events := recorder.Events()
+		require.Len(t, events, 1)
+
+		var caller reportedCaller
+		require.NoError(t, json.Unmarshal(events[0].Payload, &caller))
+		return caller
+	}
+
+	return logs.NewLogger(client), reported
+}
+
+func here() (string, int) {
+	_, file, line, _ := runtime.Caller(1)
+	return file, line
+}
+
+func helperError(logger *logs.Logger, message string) {
+	logs.Helper()
+	_ = logger.Error(message)
+}
+
+func nestedHelperError(logger *logs.Logger, message string) {
+	logs.Helper()
+	helperError(logger, message)
+}
+
+func skippingError(logger *logs.Logger, message string) {
+	_ = logger.WithCallerSkip(1).Error(message)
+}
+
+func helperSlog(logger *slog.Logger, message string) {
+	logs.Helper()
+	logger.Error(message)
+}
+
+func TestHelperReportsItsCaller(t *testing.T) {
+	logger, reported := newCallerLogger(t)
+
+	file, line := here()
+	nestedHelperError(logger, "through helpers")
+
+	assert.Equal(t, reportedCaller{File: file, LineNumber: line + 1}, reported())
+}
+
+func TestWithCallerSkipReportsOuterCaller(t *testing.T) {
+	logger, reported := newCallerLogger(t)
+
+	file, line := here()
+	skippingError(logger, "through a wrapper")
+
+	assert.Equal(t, reportedCaller{File: file, LineNumber: line + 1}, reported())
+}
+
+func TestHelperAppliesToSlog(t *testing.T) {
+	logger, reported := newCallerLogger(t)
+
+	file, line := here()
+	helperSlog(slog.New(logs.NewHandler(logger.Client())), "through slog")
+
+	assert.Equal(t, reportedCaller{File: file, LineNumber: line + 1}, reported())
+}
@@ -0,0 +1,117 @@
+package logs
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// maxErrorChain bounds the errors recorded from one chain.
+const maxErrorChain = 64
+
+// ErrorLink is one error in the chain recorded with a report.
+type ErrorLink struct {
+	Message string `json:"message"`
+	// Type is the error's Go type, such as *fs.PathError.
+	Type string `json:"type"`
+	// Stack is the stack the error carries, if any.
+	Stack string `json:"stack,omitempty"`
+	// Parent is the index of the error that wraps this one, or -1 for the
+	// outermost error.
+	Parent int `json:"parent"`
+}
+
+// ErrorChain returns err and every error it wraps, outermost first. Errors
+// with Unwrap() []error, such as those from errors.Join, are walked depth
+// first, and each link records the index of the error that wrapped it. An
+// error whose methods panic, such as a typed nil pointer, is recorded as fmt
+// prints it and not walked further.
+func ErrorChain(err error) []ErrorLink {
+	var chain []ErrorLink
+
+	var walk func(err error, parent int)
+	walk = func(err error, parent int) {
+		if err == nil || len(chain) >= maxErrorChain {
+			return
+		}
+
+		index := len(chain)
+		chain = append(chain, ErrorLink{
+			Message: fmt.Sprint(err),
+			Type:    fmt.Sprintf("%T", err),
+			Stack:   errorStack(err),
+			Parent:  parent,
+		})
+
+		for _, wrapped := range unwrapErrors(err) {
+			walk(wrapped, index)
+		}
+	}
+	walk(err, -1)
+
+	return chain
+}
+
+// unwrapErrors returns the errors err wraps, or none if its Unwrap method
+// panics.
+func unwrapErrors(err error) (wrapped []error) {
+	defer func() {
+		if recover() != nil {
+			wrapped = nil
+		}
+	}()
+
+	switch err := err.(type) {
+	case interface{ Unwrap() []error }:
+		return err.Unwrap()
+	case interface{ Unwrap() error }:
+		return []error{err.Unwrap()}
+	}
+
+	return nil
+}
+
+// errorStack returns the stack err carries: a *BugFixes stack, a Stack()
+// []byte or Callers() []uintptr method, or a StackTrace method such as
+// github.com/pkg/errors provides, formatted with %+v. It is empty if the
+// method panics.
+func errorStack(err error) (stack string) {
+	defer func() {
+		if recover() != nil {
+			stack = ""
+		}
+	}()
+
+	switch err := err.(type) {
+	case *BugFixes:
+		return string(err.Stack)
+	case interface{ Stack() []byte }:
+		return string(err.Stack())
+	case interface{ Callers() []uintptr }:
+		return formatCallers(err.Callers())
+	}
+
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return ""
+	}
+
+	return fmt.Sprintf("%+v", method.Call(nil)[0].Interface())
+}
+
+func formatCallers(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return ""
+	}
+
+	var out bytes.Buffer
+	frames := runtime.CallersFrames(pcs)
+	for more := true; more; {
+		var frame runtime.Frame
+		frame, more = frames.Next()
+		fmt.Fprintf(&out, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+	}
+
+	return out.String()
+}
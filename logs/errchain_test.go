@@ -0,0 +1,111 @@
+package logs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime"
+	"testing"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+	"github.com/bugfixes/go-bugfixes/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stackError struct {
+	pcs []uintptr
+}
+
+func newStackError() *stackError {
+	pcs := make([]uintptr, 8)
+	return &stackError{pcs: pcs[:runtime.Callers(1, pcs)]}
+}
+
+func (e *stackError) Error() string      { return "timed out" }
+func (e *stackError) Callers() []uintptr { return e.pcs }
+
+func TestErrorChainWalksTrees(t *testing.T) {
+	pathErr := &fs.PathError{Op: "open", Path: "config.yaml", Err: fs.ErrNotExist}
+	err := fmt.Errorf("load: %w", errors.Join(pathErr, newStackError()))
+
+	chain := logs.ErrorChain(err)
+	require.Len(t, chain, 5)
+
+	types := make([]string, len(chain))
+	parents := make([]int, len(chain))
+	for i, link := range chain {
+		types[i] = link.Type
+		parents[i] = link.Parent
+	}
+	assert.Equal(t, []string{"*fmt.wrapError", "*errors.joinError", "*fs.PathError", "*errors.errorString", "*logs_test.stackError"}, types)
+	assert.Equal(t, []int{-1, 0, 1, 2, 1}, parents)
+	assert.Equal(t, "open config.yaml: file does not exist", chain[2].Message)
+	assert.Contains(t, chain[4].Stack, "logs_test.newStackError")
+	assert.Empty(t, chain[2].Stack)
+}
+
+type tracedError struct{}
+
+func (tracedError) Error() string        { return "traced" }
+func (tracedError) StackTrace() []string { return []string{"main.run"} }
+
+func TestErrorChainFormatsStackTraces(t *testing.T) {
+	chain := logs.ErrorChain(fmt.Errorf("run: %w", tracedError{}))
+
+	require.Len(t, chain, 2)
+	assert.Equal(t, "[main.run]", chain[1].Stack)
+}
+
+func TestErrorChainSurvivesTypedNilErrors(t *testing.T) {
+	var pathErr *fs.PathError
+	var stackErr *stackError
+	err := fmt.Errorf("load: %w", errors.Join(pathErr, stackErr))
+
+	var chain []logs.ErrorLink
+	require.NotPanics(t, func() { chain = logs.ErrorChain(err) })
+	require.Len(t, chain, 4)
+	assert.Equal(t, "<nil>", chain[2].Message)
+	assert.Equal(t, "*fs.PathError", chain[2].Type)
+	assert.Equal(t, "timed out", chain[3].Message)
+	assert.Empty(t, chain[3].Stack)
+}
+
+func TestBugFixesUnwraps(t *testing.T) {
+	pathErr := &fs.PathError{Op: "open", Path: "config.yaml", Err: fs.ErrNotExist}
+	err := logs.NewBugFixes(fmt.Errorf("load: %w", pathErr))
+
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	var target *fs.PathError
+	require.ErrorAs(t, err, &target)
+	assert.Same(t, pathErr, target)
+	assert.Equal(t, "load: open config.yaml: file does not exist", err.Error())
+}
+
+func TestReportsCarryErrorChain(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	client := bugfixes.NewClient(bugfixes.Config{Transport: recorder, LogLevel: bugfixes.ERROR})
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+	logger := logs.NewLogger(client)
+
+	_ = logger.Errorf("sync failed: %w", errors.Join(context.Canceled, fs.ErrClosed))
+	_ = logger.Errorf("plain failure")
+	require.NoError(t, client.Flush(context.Background()))
+
+	chains := make(map[string][]logs.ErrorLink)
+	for _, event := range recorder.Events() {
+		var payload struct {
+			Log   string           `json:"log"`
+			Chain []logs.ErrorLink `json:"chain"`
+		}
+		require.NoError(t, json.Unmarshal(event.Payload, &payload))
+		chains[payload.Log] = payload.Chain
+	}
+
+	require.Len(t, chains, 2)
+	require.Len(t, chains["sync failed: context canceled\nfile already closed"], 4)
+	assert.Equal(t, "context canceled", chains["sync failed: context canceled\nfile already closed"][2].Message)
+	assert.Nil(t, chains["plain failure"])
+}
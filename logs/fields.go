@@ -0,0 +1,162 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// badKey is the key given to a value in With without a string key before
+// it, as in log/slog.
+const badKey = "!BADKEY"
+
+type field struct {
+	key   string
+	value any
+}
+
+// withKeyvals returns a copy of fields with alternating keys and values
+// added. Keys must be strings; slog.Attr values are added as attributes.
+func withKeyvals(fields map[string]any, keyvals []any) map[string]any {
+	if len(keyvals) == 0 {
+		return fields
+	}
+
+	out := cloneFields(fields)
+	if out == nil {
+		out = make(map[string]any, len(keyvals)/2)
+	}
+	for len(keyvals) > 0 {
+		switch key := keyvals[0].(type) {
+		case slog.Attr:
+			addAttr(out, key)
+			keyvals = keyvals[1:]
+		case string:
+			if len(keyvals) == 1 {
+				out[badKey] = key
+				return out
+			}
+			out[key] = fieldValue(keyvals[1])
+			keyvals = keyvals[2:]
+		default:
+			out[badKey] = fieldValue(key)
+			keyvals = keyvals[1:]
+		}
+	}
+
+	return out
+}
+
+// withFields returns a copy of fields with extra added.
+func withFields(fields, extra map[string]any) map[string]any {
+	if len(extra) == 0 {
+		return fields
+	}
+
+	out := cloneFields(fields)
+	if out == nil {
+		out = make(map[string]any, len(extra))
+	}
+	for key, value := range extra {
+		out[key] = fieldValue(value)
+	}
+
+	return out
+}
+
+// fieldValue returns v in a form that encodes to JSON: errors become their
+// message, durations their string form, and values encoding/json rejects,
+// such as channels, functions and NaN, their fmt representation.
+func fieldValue(v any) any {
+	switch v := v.(type) {
+	case nil, string, bool, int, int64, uint, uint64, time.Time:
+		return v
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		return v
+	case time.Duration:
+		return v.String()
+	case error:
+		return v.Error()
+	case map[string]any:
+		group := make(map[string]any, len(v))
+		for key, value := range v {
+			group[key] = fieldValue(value)
+		}
+		return group
+	}
+
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+
+	return v
+}
+
+// flattenFields returns fields sorted by key, with groups flattened into
+// dotted keys.
+func flattenFields(fields map[string]any) []field {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var flat []field
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		if group, ok := fields[key].(map[string]any); ok {
+			for _, nested := range flattenFields(group) {
+				flat = append(flat, field{key: key + "." + nested.key, value: nested.value})
+			}
+			continue
+		}
+		flat = append(flat, field{key: key, value: textValue(fields[key])})
+	}
+
+	return flat
+}
+
+// textValue returns composite values as JSON, which logfmt cannot encode
+// directly.
+func textValue(v any) any {
+	if v == nil {
+		return nil
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.Struct:
+		if _, ok := v.(time.Time); ok {
+			return v
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%+v", v)
+		}
+		return string(encoded)
+	}
+
+	return v
+}
+
+// cloneFields copies fields and the groups nested in them.
+func cloneFields(fields map[string]any) map[string]any {
+	if fields == nil {
+		return nil
+	}
+
+	clone := make(map[string]any, len(fields))
+	for key, value := range fields {
+		if group, ok := value.(map[string]any); ok {
+			value = cloneFields(group)
+		}
+		clone[key] = value
+	}
+
+	return clone
+}
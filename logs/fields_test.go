@@ -0,0 +1,81 @@
+package logs
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithKeyvals(t *testing.T) {
+	fields := withKeyvals(nil, []any{
+		"user", "ada",
+		slog.Group("req", slog.Int("status", 200)),
+		7,
+		"dangling",
+	})
+
+	if fields["user"] != "ada" {
+		t.Fatalf("expected the key/value pair, got %v", fields)
+	}
+	if group, ok := fields["req"].(map[string]any); !ok || group["status"] != int64(200) {
+		t.Fatalf("expected the slog group as a nested field, got %v", fields["req"])
+	}
+	if fields[badKey] != "dangling" {
+		t.Fatalf("expected the dangling key under %s, got %v", badKey, fields[badKey])
+	}
+}
+
+func TestFieldValuesEncode(t *testing.T) {
+	fields := withFields(nil, map[string]any{
+		"nan":     math.NaN(),
+		"ch":      make(chan int),
+		"fn":      func() {},
+		"err":     errors.New("boom"),
+		"timeout": 3 * time.Second,
+		"tags":    []string{"a", "b"},
+	})
+
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatalf("expected fields to encode, got %v", err)
+	}
+	for _, want := range []string{`"nan":"NaN"`, `"err":"boom"`, `"timeout":"3s"`, `"tags":["a","b"]`} {
+		if !strings.Contains(string(encoded), want) {
+			t.Fatalf("expected %s in %s", want, encoded)
+		}
+	}
+}
+
+func TestLogFormatSortsFields(t *testing.T) {
+	b := &BugFixes{
+		Level:        INFO,
+		FormattedLog: "served",
+		Fields: map[string]any{
+			"tenant": "acme",
+			"req":    map[string]any{"status": 200, "path": "/a b"},
+			"tags":   []string{"x"},
+		},
+	}
+	b.logFormat()
+
+	want := `req.path="/a b" req.status=200 tags="[\"x\"]" tenant=acme`
+	if !strings.HasSuffix(strings.TrimSpace(b.LogFmt), want) {
+		t.Fatalf("expected sorted fields %q, got %q", want, b.LogFmt)
+	}
+}
+
+func TestMakePrettyPrintsFields(t *testing.T) {
+	b := Local().With("tenant", "acme", "attempt", 2)
+	b.Level = INFO
+	b.FormattedLog = "retrying"
+
+	stdout, _ := captureStandardStreams(t, b.makePretty)
+
+	if !strings.Contains(stdout, "retrying attempt=2 tenant=acme") {
+		t.Fatalf("expected the fields after the message, got %q", stdout)
+	}
+}
@@ -0,0 +1,230 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+// Logger logs and reports through a bugfixes.Client instead of the default
+// config, so loggers for different clients do not share credentials, queues
+// or sampling state. Its methods match the package-level functions.
+//
+// A Logger is never modified once made; With and WithFields return new ones.
+// Each call builds a fresh *BugFixes event, so one Logger can be shared
+// between goroutines.
+type Logger struct {
+	client *bugfixes.Client
+	fields map[string]any
+
+	localOnly bool
+	skipDepth int
+	agentID   string
+	secret    string
+	config    *bugfixes.Config
+}
+
+// NewLogger returns a Logger bound to client. A nil client means
+// bugfixes.DefaultClient.
+func NewLogger(client *bugfixes.Client) *Logger {
+	if client == nil {
+		client = bugfixes.DefaultClient()
+	}
+
+	return &Logger{client: client}
+}
+
+// std backs the package-level functions.
+var std = NewLogger(nil)
+
+// Client returns the client l reports through.
+func (l *Logger) Client() *bugfixes.Client {
+	return l.client
+}
+
+// With returns a Logger that adds keyvals, alternating string keys and
+// values, to every entry as structured fields. slog.Attr values may be
+// passed in place of a pair.
+func (l *Logger) With(keyvals ...any) *Logger {
+	clone := *l
+	clone.fields = withKeyvals(l.fields, keyvals)
+	return &clone
+}
+
+// WithFields returns a Logger that adds fields to every entry.
+func (l *Logger) WithFields(fields map[string]any) *Logger {
+	clone := *l
+	clone.fields = withFields(l.fields, fields)
+	return &clone
+}
+
+// WithCallerSkip returns a Logger that reports the caller skip frames
+// further up the stack, for wrappers that cannot call Helper. It replaces
+// any skip set before.
+func (l *Logger) WithCallerSkip(skip int) *Logger {
+	clone := *l
+	clone.skipDepth = skip
+	return &clone
+}
+
+// event returns a new entry with l's settings, bound to ctx.
+func (l *Logger) event(ctx context.Context) *BugFixes {
+	return &BugFixes{
+		LocalOnly:         l.localOnly,
+		SkipDepthOverride: l.skipDepth,
+		AgentID:           l.agentID,
+		Secret:            l.secret,
+		Config:            l.config,
+		Fields:            cloneFields(l.fields),
+		ctx:               ctx,
+		client:            l.client,
+	}
+}
+
+// logAt is the shared implementation for string-returning log levels.
+func (l *Logger) logAt(ctx context.Context, level, format string, inputs ...interface{}) string {
+	e := l.event(ctx)
+	e.Level = level
+	e.FormattedLog = fmt.Sprintf(format, inputs...)
+
+	if !e.LocalOnly {
+		if levelCapturesStack(level) {
+			e.Stack = debug.Stack()
+		}
+		e.DoReporting()
+	}
+
+	display := strings.ToUpper(level[:1]) + level[1:]
+	return fmt.Sprintf("%s: %s", display, e.FormattedLog)
+}
+
+func (l *Logger) errorf(ctx context.Context, format string, inputs ...interface{}) error {
+	e := l.event(ctx)
+	e.Level = "error"
+	e.FormattedError = fmt.Errorf(format, inputs...)
+	e.FormattedLog = e.FormattedError.Error()
+
+	if !e.LocalOnly {
+		e.Stack = debug.Stack()
+		e.DoReporting()
+	}
+
+	return e.FormattedError
+}
+
+func (l *Logger) fatalf(ctx context.Context, format string, inputs ...interface{}) {
+	e := l.event(ctx)
+	e.Level = "fatal"
+	e.FormattedLog = fmt.Sprintf(format, inputs...)
+	e.Stack = debug.Stack()
+
+	if !e.LocalOnly {
+		e.DoReporting()
+	}
+
+	panic(e)
+}
+
+func (l *Logger) Error(inputs ...interface{}) error {
+	return l.Errorf(variadicFormat(inputs), inputs...)
+}
+func (l *Logger) Errorf(format string, inputs ...interface{}) error {
+	return l.errorf(context.Background(), format, inputs...)
+}
+
+// ReportContext is the package-level ReportContext through l's client. A nil
+// err reports nothing and returns a zero Result.
+func (l *Logger) ReportContext(ctx context.Context, err error) (bugfixes.Result, error) {
+	if err == nil {
+		return bugfixes.Result{}, nil
+	}
+
+	e := l.event(ctx)
+	e.Level = "error"
+	e.FormattedLog = err.Error()
+	e.FormattedError = err
+	e.Err = err
+	e.Stack = debug.Stack()
+
+	return e.ReportContext(ctx)
+}
+
+func (l *Logger) Info(inputs ...interface{}) string {
+	return l.Infof(variadicFormat(inputs), inputs...)
+}
+func (l *Logger) Infof(format string, inputs ...interface{}) string {
+	return l.logAt(context.Background(), INFO, format, inputs...)
+}
+
+func (l *Logger) Debug(inputs ...interface{}) string {
+	return l.Debugf(variadicFormat(inputs), inputs...)
+}
+func (l *Logger) Debugf(format string, inputs ...interface{}) string {
+	return l.logAt(context.Background(), DEBUG, format, inputs...)
+}
+
+func (l *Logger) Log(inputs ...interface{}) string {
+	return l.Logf(variadicFormat(inputs), inputs...)
+}
+func (l *Logger) Logf(format string, inputs ...interface{}) string {
+	return l.logAt(context.Background(), LOG, format, inputs...)
+}
+
+func (l *Logger) Warn(inputs ...interface{}) string {
+	return l.Warnf(variadicFormat(inputs), inputs...)
+}
+func (l *Logger) Warnf(format string, inputs ...interface{}) string {
+	return l.logAt(context.Background(), WARN, format, inputs...)
+}
+
+func (l *Logger) Fatal(inputs ...interface{}) {
+	l.Fatalf(variadicFormat(inputs), inputs...)
+}
+func (l *Logger) Fatalf(format string, inputs ...interface{}) {
+	l.fatalf(context.Background(), format, inputs...)
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, inputs ...interface{}) error {
+	return l.ErrorfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func (l *Logger) ErrorfCtx(ctx context.Context, format string, inputs ...interface{}) error {
+	return l.errorf(ctx, format, inputs...)
+}
+
+func (l *Logger) InfoCtx(ctx context.Context, inputs ...interface{}) string {
+	return l.InfofCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func (l *Logger) InfofCtx(ctx context.Context, format string, inputs ...interface{}) string {
+	return l.logAt(ctx, INFO, format, inputs...)
+}
+
+func (l *Logger) DebugCtx(ctx context.Context, inputs ...interface{}) string {
+	return l.DebugfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func (l *Logger) DebugfCtx(ctx context.Context, format string, inputs ...interface{}) string {
+	return l.logAt(ctx, DEBUG, format, inputs...)
+}
+
+func (l *Logger) LogCtx(ctx context.Context, inputs ...interface{}) string {
+	return l.LogfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func (l *Logger) LogfCtx(ctx context.Context, format string, inputs ...interface{}) string {
+	return l.logAt(ctx, LOG, format, inputs...)
+}
+
+func (l *Logger) WarnCtx(ctx context.Context, inputs ...interface{}) string {
+	return l.WarnfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func (l *Logger) WarnfCtx(ctx context.Context, format string, inputs ...interface{}) string {
+	return l.logAt(ctx, WARN, format, inputs...)
+}
+
+func (l *Logger) FatalCtx(ctx context.Context, inputs ...interface{}) {
+	l.FatalfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func (l *Logger) FatalfCtx(ctx context.Context, format string, inputs ...interface{}) {
+	l.fatalf(ctx, format, inputs...)
+}
@@ -0,0 +1,121 @@
+package logs_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+	"github.com/bugfixes/go-bugfixes/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerReportsThroughItsClient(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	client := bugfixes.NewClient(bugfixes.Config{Transport: recorder, LogLevel: bugfixes.ERROR})
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	logger := logs.NewLogger(client)
+	err := logger.Errorf("charge failed: %s", "card declined")
+	require.EqualError(t, err, "charge failed: card declined")
+	logger.Info("below the reporting level")
+	require.NoError(t, client.Flush(context.Background()))
+
+	events := recorder.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, bugfixes.ERROR, events[0].Level)
+	assert.Contains(t, string(events[0].Payload), "card declined")
+}
+
+func TestNewLoggerDefaultsToDefaultClient(t *testing.T) {
+	assert.Same(t, bugfixes.DefaultClient(), logs.NewLogger(nil).Client())
+}
+
+func TestLoggerWithAddsFields(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	client := bugfixes.NewClient(bugfixes.Config{Transport: recorder, LogLevel: bugfixes.ERROR})
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	parent := logs.NewLogger(client).With("tenant", "acme")
+	child := parent.With("order", 42).WithFields(map[string]any{"amount": 9.5})
+	_ = child.Error("charge failed")
+	_ = parent.Error("refund failed")
+	require.NoError(t, client.Flush(context.Background()))
+
+	events := recorder.Events()
+	require.Len(t, events, 2)
+	payloads := make(map[string]string)
+	for _, event := range events {
+		var payload struct {
+			Log string `json:"log"`
+		}
+		require.NoError(t, json.Unmarshal(event.Payload, &payload))
+		payloads[payload.Log] = string(event.Payload)
+	}
+	assert.Contains(t, payloads["charge failed"], `"fields":{"amount":9.5,"order":42,"tenant":"acme"}`)
+	assert.Contains(t, payloads["charge failed"], `amount=9.5 order=42 tenant=acme`)
+	assert.Contains(t, payloads["refund failed"], `"fields":{"tenant":"acme"}`)
+}
+
+func TestBugFixesWithKeepsSettings(t *testing.T) {
+	base := logs.Local()
+	derived := base.With("tenant", "acme")
+
+	assert.True(t, derived.LocalOnly)
+	assert.Equal(t, map[string]any{"tenant": "acme"}, derived.Fields)
+	assert.Nil(t, base.Fields)
+}
+
+func TestSharedLoggerIsRaceFree(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	client := bugfixes.NewClient(bugfixes.Config{Transport: recorder, LogLevel: bugfixes.WARN, QueueSize: 1000})
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	logger := logs.NewLogger(client).With("shared", true)
+	local := logs.Local()
+
+	const goroutines, calls = 8, 25
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Go(func() {
+			for i := range calls {
+				message := fmt.Sprintf("worker %c call %c", 'a'+g, 'a'+i)
+				assert.Equal(t, "Warn: "+message, logger.With("worker", g).Warn(message))
+				assert.Equal(t, "Info: "+message, local.Infof("%s", message))
+				assert.EqualError(t, local.Errorf("%s", message), message)
+			}
+		})
+	}
+	wg.Wait()
+	require.NoError(t, client.Flush(context.Background()))
+
+	events := recorder.Events()
+	require.Len(t, events, goroutines*calls)
+	for _, event := range events {
+		var payload struct {
+			Log    string         `json:"log"`
+			Fields map[string]any `json:"fields"`
+		}
+		require.NoError(t, json.Unmarshal(event.Payload, &payload))
+		worker := payload.Log[len("worker ")] - 'a'
+		assert.Equal(t, map[string]any{"shared": true, "worker": float64(worker)}, payload.Fields, payload.Log)
+	}
+	assert.Nil(t, local.Fields)
+	assert.Empty(t, local.FormattedLog)
+}
+
+func TestReportContextIgnoresNilError(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	client := bugfixes.NewClient(bugfixes.Config{Transport: recorder})
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	result, err := logs.NewLogger(client).ReportContext(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Zero(t, result)
+	assert.Empty(t, recorder.Events())
+
+	assert.NotPanics(t, func() { _, _ = logs.ReportContext(context.Background(), nil) })
+}
@@ -4,13 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
-	"runtime"
-	"strconv"
-	"strings"
 	"time"
 
 	bugfixes "github.com/bugfixes/go-bugfixes"
@@ -18,8 +15,6 @@ import (
 	"github.com/go-logfmt/logfmt"
 )
 
-const logsPackagePrefix = "github.com/bugfixes/go-bugfixes/logs."
-
 type BugFixes struct {
 	FormattedLog string `json:"log"`
 	Level        string `json:"level"`
@@ -32,8 +27,18 @@ type BugFixes struct {
 	FormattedError error `json:"-"`
 	LocalOnly      bool  `json:"-"`
 
-	Bug               string
-	Err               error
+	// Fields are structured attributes sent with the report. Nested maps
+	// hold groups.
+	Fields map[string]any `json:"fields,omitempty"`
+	// Time is when the entry was logged, if the caller recorded it.
+	Time time.Time `json:"time,omitzero"`
+
+	Bug string
+	Err error `json:"-"`
+	// Chain is the error the entry was made from and every error it wraps.
+	Chain []ErrorLink `json:"chain,omitempty"`
+	// SkipDepthOverride skips that many more callers, after the logs
+	// package and any helpers, when the reported file and line are found.
 	SkipDepthOverride int
 
 	// Creds
@@ -41,6 +46,13 @@ type BugFixes struct {
 	Secret  string
 
 	Config *bugfixes.Config `json:"-"`
+
+	ctx    context.Context
+	pkg    string
+	client *bugfixes.Client
+
+	// pc is the caller's program counter, when it is already known.
+	pc uintptr
 }
 
 func NewBugFixes(err error) error {
@@ -63,60 +75,48 @@ func (b *BugFixes) SetConfig(cfg bugfixes.Config) {
 }
 
 const (
-	LOG   = "log"
-	DEBUG = "debug"
+	LOG   = bugfixes.LOG
+	DEBUG = bugfixes.DEBUG
 
-	INFO = "info"
-	WARN = "warn"
+	INFO = bugfixes.INFO
+	WARN = bugfixes.WARN
 
-	ERROR = "error"
+	ERROR = bugfixes.ERROR
 
-	CRASH = "crash"
-	PANIC = "panic"
-	FATAL = "fatal"
+	CRASH = bugfixes.CRASH
+	PANIC = bugfixes.PANIC
+	FATAL = bugfixes.FATAL
 
-	UNKNOWN = "unknown"
+	UNKNOWN = bugfixes.UNKNOWN
 )
 
 const (
-	LevelDebug   = 1
-	LevelLog     = 2
-	LevelInfo    = 3
-	LevelWarn    = 4
-	LevelError   = 5
-	LevelCrash   = 6
-	LevelUnknown = 9
+	LevelDebug   = bugfixes.LevelDebug
+	LevelLog     = bugfixes.LevelLog
+	LevelInfo    = bugfixes.LevelInfo
+	LevelWarn    = bugfixes.LevelWarn
+	LevelError   = bugfixes.LevelError
+	LevelCrash   = bugfixes.LevelCrash
+	LevelUnknown = bugfixes.LevelUnknown
 )
 
 // ConvertLevelFromString converts a level name to its numeric value.
 func ConvertLevelFromString(s string) int {
-	switch s {
-	case LOG:
-		return LevelLog
-	case DEBUG:
-		return LevelDebug
-	case INFO:
-		return LevelInfo
-	case WARN:
-		return LevelWarn
-	case ERROR:
-		return LevelError
-	case CRASH, PANIC, FATAL:
-		return LevelCrash
-	case UNKNOWN:
-		return LevelUnknown
-	default:
-		lvl, err := strconv.Atoi(s)
-		if err != nil {
-			return LevelUnknown
-		}
-		if lvl >= LevelUnknown {
-			return LevelUnknown
-		}
-		return lvl
+	return bugfixes.ConvertLevelFromString(s)
+}
+
+// Unwrap returns the error b was made from, so errors.Is and errors.As see
+// through it.
+func (b *BugFixes) Unwrap() error {
+	if b.Err != nil {
+		return b.Err
 	}
+
+	return b.FormattedError
 }
 
+// UnwrapIt returns the error e wraps directly, or nil. Use ErrorChain for
+// every error in a chain.
 func (b *BugFixes) UnwrapIt(e error) error {
 	u, ok := e.(interface {
 		Unwrap() error
@@ -128,29 +128,42 @@ func (b *BugFixes) UnwrapIt(e error) error {
 	return u.Unwrap()
 }
 
-// findCaller walks the call stack and returns the first frame outside the logs package.
-func (b *BugFixes) findCaller() {
-	var pcs [25]uintptr
-	n := runtime.Callers(1, pcs[:])
-	frames := runtime.CallersFrames(pcs[:n])
-
-	for {
-		frame, more := frames.Next()
-		if !strings.HasPrefix(frame.Function, logsPackagePrefix) {
-			b.File = frame.File
-			b.LineNumber = frame.Line
-			b.Line = strconv.Itoa(frame.Line)
-			return
-		}
-		if !more {
-			break
-		}
+func (b *BugFixes) DoReporting() {
+	cfg := b.config()
+
+	event, ok, err := b.prepare(cfg)
+	if err != nil {
+		bugfixes.ReportInternalError(cfg, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	if err := bugfixes.Submit(cfg, event); err != nil && !errors.Is(err, bugfixes.ErrDropped) {
+		bugfixes.ReportInternalError(cfg, err)
 	}
 }
 
-func (b *BugFixes) DoReporting() {
+// ReportContext is DoReporting, but waits for the report to be delivered and
+// returns the ID the server gave it. The send is bound by ctx's deadline.
+// Nothing is sent, and a zero Result is returned, when the entry is local
+// only or below the reporting level.
+func (b *BugFixes) ReportContext(ctx context.Context) (bugfixes.Result, error) {
+	b.ctx = ctx
 	cfg := b.config()
 
+	event, ok, err := b.prepare(cfg)
+	if !ok {
+		return bugfixes.Result{}, err
+	}
+
+	return bugfixes.ReportContext(ctx, cfg, event)
+}
+
+// prepare prints the entry locally and builds the event to report, if it
+// should be reported at all.
+func (b *BugFixes) prepare(cfg bugfixes.Config) (bugfixes.Event, bool, error) {
 	b.findCaller()
 
 	// Log Format
@@ -160,22 +173,58 @@ func (b *BugFixes) DoReporting() {
 	b.makePretty()
 
 	if cfg.LocalOnly {
-		return
+		return bugfixes.Event{}, false, nil
 	}
 
 	// Log level
-	reportLogLevel := ConvertLevelFromString(cfg.LogLevel)
+	reportLogLevel := ConvertLevelFromString(cfg.LevelFor(b.pkg, b.File))
 	logLevel := ConvertLevelFromString(b.Level)
 	if reportLogLevel > logLevel {
-		return
+		return bugfixes.Event{}, false, nil
+	}
+
+	if err := cfg.CheckCredentials(); err != nil {
+		return bugfixes.Event{}, false, err
+	}
+
+	if b.Chain == nil {
+		b.Chain = ErrorChain(b.cause())
 	}
 
 	body, err := json.Marshal(b)
 	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "bugfixes sendLog marshal: %+v\n", err)
-		return
+		return bugfixes.Event{}, false, fmt.Errorf("%w: log: %w", bugfixes.ErrMarshal, err)
+	}
+
+	// Without a stack, the call site is what tells two log lines apart.
+	message := b.FormattedLog
+	if b.Stack == nil {
+		message = b.File + ": " + message
+	}
+
+	event := bugfixes.NewEvent(bugfixes.KindLog, body)
+	event.Level = b.Level
+	event.Message = message
+	event.Stack = b.Stack
+	if b.ctx != nil {
+		event = event.WithContext(b.ctx)
+	}
+
+	return event, true, nil
+}
+
+// cause returns the error the entry was made from: Err, or an error
+// created by Errorf that wraps others with %w.
+func (b *BugFixes) cause() error {
+	if b.Err != nil {
+		return b.Err
 	}
-	go b.sendLogBody(cfg, body)
+	switch b.FormattedError.(type) {
+	case interface{ Unwrap() error }, interface{ Unwrap() []error }:
+		return b.FormattedError
+	}
+
+	return nil
 }
 
 func (b *BugFixes) logFormat() {
@@ -183,64 +232,35 @@ func (b *BugFixes) logFormat() {
 	lf := logfmt.NewEncoder(&out)
 
 	if err := lf.EncodeKeyval("path", b.File); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "logfmt path: %v", err)
+		b.internalError(fmt.Errorf("%w: logfmt path: %w", bugfixes.ErrMarshal, err))
 	}
 	if err := lf.EncodeKeyval("level", b.Level); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "logfmt level: %v", err)
+		b.internalError(fmt.Errorf("%w: logfmt level: %w", bugfixes.ErrMarshal, err))
 	}
 	if err := lf.EncodeKeyval("msg", b.FormattedLog); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "logfmt msg: %v", err)
+		b.internalError(fmt.Errorf("%w: logfmt msg: %w", bugfixes.ErrMarshal, err))
 	}
 	if err := lf.EncodeKeyval("time", time.Now()); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "logfmt time: %v", err)
+		b.internalError(fmt.Errorf("%w: logfmt time: %w", bugfixes.ErrMarshal, err))
 	}
 	if err := lf.EncodeKeyval("line", b.Line); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "logfmt line: %v", err)
+		b.internalError(fmt.Errorf("%w: logfmt line: %w", bugfixes.ErrMarshal, err))
+	}
+	for _, field := range flattenFields(b.Fields) {
+		if err := lf.EncodeKeyval(field.key, field.value); err != nil {
+			b.internalError(fmt.Errorf("%w: logfmt %s: %w", bugfixes.ErrMarshal, field.key, err))
+		}
 	}
 
 	if err := lf.EndRecord(); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "logfmt endrecord: %v", err)
+		b.internalError(fmt.Errorf("%w: logfmt endrecord: %w", bugfixes.ErrMarshal, err))
 	}
 
 	b.LogFmt = out.String()
 }
 
-func (b *BugFixes) sendLogBody(cfg bugfixes.Config, body []byte) {
-	if cfg.AgentKey == "" || cfg.AgentSecret == "" {
-		_, _ = fmt.Fprint(os.Stderr, "cant send to server till you have created an agent and set the keys\n")
-		if cfg.AgentKey == "" {
-			_, _ = fmt.Fprint(os.Stderr, "env: BUGFIXES_AGENT_KEY missing\n")
-		}
-		if cfg.AgentSecret == "" {
-			_, _ = fmt.Fprint(os.Stderr, "env: BUGFIXES_AGENT_SECRET missing\n")
-		}
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), bugfixes.DefaultTimeout)
-	defer cancel()
-
-	request, err := http.NewRequestWithContext(ctx, "POST", cfg.LogEndpoint(), bytes.NewBuffer(body))
-	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "bugfixes sendLog newRequest: %+v\n", err)
-		return
-	}
-	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set("X-API-KEY", cfg.AgentKey)
-	request.Header.Set("X-API-SECRET", cfg.AgentSecret)
-
-	client := cfg.GetHTTPClient()
-	resp, err := client.Do(request)
-	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "bugfixes sendLog do: %+v\n", err)
-		return
-	}
-	if resp != nil && resp.Body != nil {
-		if err := resp.Body.Close(); err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "bugfixes sendLog close: %+v\n", err)
-			return
-		}
-	}
+func (b *BugFixes) internalError(err error) {
+	bugfixes.ReportInternalError(b.config(), err)
 }
 
 func (b *BugFixes) makePretty() {
@@ -269,8 +289,12 @@ func (b *BugFixes) makePretty() {
 		cW(out, true, bWhite, "%s:", b.Level)
 	}
 
+	for _, field := range flattenFields(b.Fields) {
+		log += fmt.Sprintf(" %s=%v", field.key, field.value)
+	}
+
 	// print to stdout if the level is high enough
-	reportLogLevel := ConvertLevelFromString(cfg.LogLevel)
+	reportLogLevel := ConvertLevelFromString(cfg.LevelFor(b.pkg, b.File))
 	logLevel := ConvertLevelFromString(b.Level)
 	writer := localLogWriter(b.Level)
 	if logLevel >= reportLogLevel || reportLogLevel == LevelUnknown || cfg.LocalOnly {
@@ -297,6 +321,9 @@ func localLogWriter(level string) io.Writer {
 
 func (b *BugFixes) config() bugfixes.Config {
 	cfg := bugfixes.GetDefaultConfig()
+	if b != nil && b.client != nil {
+		cfg = b.client.Config()
+	}
 	if b != nil && b.Config != nil {
 		cfg = cfg.Merge(*b.Config)
 	}
@@ -1,8 +1,11 @@
 package logs
 
 import (
+	"context"
+	"errors"
 	"io"
 	"os"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -89,3 +92,125 @@ func TestDoReportingLocalStreamsByLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestDoReportingSendsInternalErrorsToHandler(t *testing.T) {
+	var reported []error
+	entry := &BugFixes{
+		FormattedLog: "internal error test",
+		Level:        ERROR,
+		Config: &bugfixes.Config{
+			Server:          "https://bugfixes.invalid",
+			LogLevel:        ERROR,
+			OnInternalError: func(err error) { reported = append(reported, err) },
+		},
+	}
+
+	_, stderr := captureStandardStreams(t, entry.DoReporting)
+
+	if len(reported) != 1 || !errors.Is(reported[0], bugfixes.ErrMissingCredentials) {
+		t.Fatalf("expected a missing credentials error, got %v", reported)
+	}
+	if strings.Contains(stderr, "BUGFIXES_AGENT_KEY") {
+		t.Fatalf("expected nothing printed about credentials, got %q", stderr)
+	}
+}
+
+func TestReportContextWaitsForDelivery(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	entry := &BugFixes{
+		FormattedLog: "report context test",
+		Level:        ERROR,
+		Config: &bugfixes.Config{
+			LogLevel:  ERROR,
+			Transport: recorder,
+		},
+	}
+
+	var result bugfixes.Result
+	var err error
+	_, _ = captureStandardStreams(t, func() {
+		result, err = entry.ReportContext(context.Background())
+	})
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	events := recorder.Events()
+	if len(events) != 1 {
+		t.Fatalf("expected the event to be delivered before returning, got %d", len(events))
+	}
+	if result.ClientID == "" || result.ClientID != events[0].ID {
+		t.Fatalf("expected the result to carry the event ID, got %+v", result)
+	}
+}
+
+func TestInfoCtxAttachesContextValues(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	recorder := bugfixes.NewRecorder()
+	entry := &BugFixes{
+		Config: &bugfixes.Config{
+			LogLevel:  INFO,
+			Transport: recorder,
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), bugfixes.RequestIDKey, "req-42")
+	ctx = bugfixes.WithTags(ctx, map[string]string{"tenant": "acme"})
+	_, _ = captureStandardStreams(t, func() {
+		entry.InfoCtx(ctx, "context test")
+	})
+	if err := bugfixes.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	events := recorder.Events()
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %d", len(events))
+	}
+	if events[0].RequestID != "req-42" || events[0].Tags["tenant"] != "acme" {
+		t.Fatalf("expected the context values on the event, got %+v", events[0])
+	}
+}
+
+func TestMakePrettyUsesLevelRules(t *testing.T) {
+	rules, err := bugfixes.ParseLevelRules("github.com/acme/billing/...=debug,*=warn")
+	if err != nil {
+		t.Fatalf("parse rules: %v", err)
+	}
+	cfg := bugfixes.Config{LogLevel: ERROR, LogLevels: rules}
+
+	stdout, _ := captureStandardStreams(t, func() {
+		billing := &BugFixes{Level: DEBUG, FormattedLog: "billing detail", File: "/src/billing/charge.go", pkg: "github.com/acme/billing/charge", Config: &cfg}
+		billing.makePretty()
+
+		other := &BugFixes{Level: DEBUG, FormattedLog: "other detail", File: "/src/shop/cart.go", pkg: "github.com/acme/shop", Config: &cfg}
+		other.makePretty()
+	})
+
+	if !strings.Contains(stdout, "billing detail") {
+		t.Fatalf("expected the billing rule to allow debug output, got %q", stdout)
+	}
+	if strings.Contains(stdout, "other detail") {
+		t.Fatalf("expected the catch-all rule to hide debug output, got %q", stdout)
+	}
+}
+
+func TestHelperPackageMarksItsFunctions(t *testing.T) {
+	inPackage := runtime.Frame{Function: "example.com/wrap.Errorf"}
+	if isHelperFrame(inPackage) {
+		t.Fatal("expected an unregistered package not to be a helper")
+	}
+
+	HelperPackage("example.com/wrap")
+	t.Cleanup(func() { helperPackages.Delete("example.com/wrap") })
+
+	for _, function := range []string{"example.com/wrap.Errorf", "example.com/wrap.(*Logger).Warn"} {
+		if !isHelperFrame(runtime.Frame{Function: function}) {
+			t.Fatalf("expected %s to be a helper", function)
+		}
+	}
+	if isHelperFrame(runtime.Frame{Function: "example.com/wrapper.Errorf"}) {
+		t.Fatal("expected only the registered package to be a helper")
+	}
+}
@@ -1,9 +1,11 @@
 package logs
 
 import (
+	"context"
 	"fmt"
-	"runtime/debug"
 	"strings"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
 )
 
 func Local(skipDepthOverride ...int) *BugFixes {
@@ -14,6 +16,62 @@ func Local(skipDepthOverride ...int) *BugFixes {
 	return b
 }
 
+// With returns a Logger for the default client that adds keyvals to every
+// entry as structured fields. See Logger.With.
+func With(keyvals ...any) *Logger {
+	return std.With(keyvals...)
+}
+
+// WithFields returns a Logger for the default client that adds fields to
+// every entry.
+func WithFields(fields map[string]any) *Logger {
+	return std.WithFields(fields)
+}
+
+// With returns a copy of b's settings, without any logged entry, that adds
+// keyvals to every entry as structured fields. See Logger.With.
+func (b *BugFixes) With(keyvals ...any) *BugFixes {
+	return b.logger().With(keyvals...).event(b.context())
+}
+
+// WithFields is With for a map of fields.
+func (b *BugFixes) WithFields(fields map[string]any) *BugFixes {
+	return b.logger().WithFields(fields).event(b.context())
+}
+
+// logger returns a Logger with b's settings. The logging methods on
+// *BugFixes go through it and leave b untouched, so one *BugFixes, such as
+// the result of Local, can be shared between goroutines.
+func (b *BugFixes) logger() *Logger {
+	if b == nil {
+		return std
+	}
+
+	l := &Logger{
+		client:    b.client,
+		fields:    b.Fields,
+		localOnly: b.LocalOnly,
+		skipDepth: b.SkipDepthOverride,
+		agentID:   b.AgentID,
+		secret:    b.Secret,
+	}
+	if b.Config != nil {
+		cfg := *b.Config
+		l.config = &cfg
+	}
+
+	return l
+}
+
+// context returns the context b is bound to, or context.Background.
+func (b *BugFixes) context() context.Context {
+	if b == nil || b.ctx == nil {
+		return context.Background()
+	}
+
+	return b.ctx
+}
+
 // variadicFormat builds a "%v, %v, ..." format string for variadic inputs.
 func variadicFormat(inputs []interface{}) string {
 	format := strings.Repeat("%v, ", len(inputs))
@@ -30,30 +88,15 @@ func levelCapturesStack(level string) bool {
 	}
 }
 
-// logAt is the shared implementation for string-returning log levels.
-func (b *BugFixes) logAt(level, format string, inputs ...interface{}) string {
-	if b == nil {
-		return (&BugFixes{LocalOnly: false}).logAt(level, format, inputs...)
-	}
-
-	b.Level = level
-	b.FormattedLog = fmt.Sprintf(format, inputs...)
-
-	if !b.LocalOnly {
-		if levelCapturesStack(level) {
-			b.Stack = debug.Stack()
-		}
-		b.DoReporting()
-	}
-
-	display := strings.ToUpper(level[:1]) + level[1:]
-	return fmt.Sprintf("%s: %s", display, b.FormattedLog)
-}
-
 // Error implements the error interface.
 func (b *BugFixes) Error() string {
-	if b.Err == nil {
+	switch {
+	case b.Err == nil && b.Bug == "":
+		return b.FormattedLog
+	case b.Err == nil:
 		return b.Bug
+	case b.Bug == "":
+		return b.Err.Error()
 	}
 	return fmt.Sprintf("%s: %s", b.Bug, b.Err.Error())
 }
@@ -64,24 +107,19 @@ func Error(inputs ...interface{}) error {
 }
 
 func Errorf(format string, inputs ...interface{}) error {
-	return (&BugFixes{LocalOnly: false}).Errorf(format, inputs...)
+	return std.Errorf(format, inputs...)
 }
 
 func (b *BugFixes) Errorf(format string, inputs ...interface{}) error {
-	if b == nil {
-		return Errorf(format, inputs...)
-	}
-
-	b.Level = "error"
-	b.FormattedLog = fmt.Sprintf(format, inputs...)
-	b.FormattedError = fmt.Errorf(format, inputs...)
-
-	if !b.LocalOnly {
-		b.Stack = debug.Stack()
-		b.DoReporting()
-	}
+	return b.logger().errorf(b.context(), format, inputs...)
+}
 
-	return b.FormattedError
+// ReportContext reports err at error level and waits for it to be
+// delivered, returning the event ID the server assigned. The send is bound
+// by ctx's deadline and carries the request ID, trace ID and tags in ctx.
+// A nil err reports nothing and returns a zero Result.
+func ReportContext(ctx context.Context, err error) (bugfixes.Result, error) {
+	return std.ReportContext(ctx, err)
 }
 
 // Info / Infof
@@ -90,10 +128,10 @@ func (b *BugFixes) Info(inputs ...interface{}) string {
 	return b.Infof(variadicFormat(inputs), inputs...)
 }
 func Infof(format string, inputs ...interface{}) string {
-	return (&BugFixes{LocalOnly: false}).Infof(format, inputs...)
+	return std.Infof(format, inputs...)
 }
 func (b *BugFixes) Infof(format string, inputs ...interface{}) string {
-	return b.logAt("info", format, inputs...)
+	return b.logger().logAt(b.context(), INFO, format, inputs...)
 }
 
 // Debug / Debugf
@@ -102,10 +140,10 @@ func (b *BugFixes) Debug(inputs ...interface{}) string {
 	return b.Debugf(variadicFormat(inputs), inputs...)
 }
 func Debugf(format string, inputs ...interface{}) string {
-	return (&BugFixes{LocalOnly: false}).Debugf(format, inputs...)
+	return std.Debugf(format, inputs...)
 }
 func (b *BugFixes) Debugf(format string, inputs ...interface{}) string {
-	return b.logAt("debug", format, inputs...)
+	return b.logger().logAt(b.context(), DEBUG, format, inputs...)
 }
 
 // Log / Logf
@@ -114,10 +152,10 @@ func (b *BugFixes) Log(inputs ...interface{}) string {
 	return b.Logf(variadicFormat(inputs), inputs...)
 }
 func Logf(format string, inputs ...interface{}) string {
-	return (&BugFixes{LocalOnly: false}).Logf(format, inputs...)
+	return std.Logf(format, inputs...)
 }
 func (b *BugFixes) Logf(format string, inputs ...interface{}) string {
-	return b.logAt("log", format, inputs...)
+	return b.logger().logAt(b.context(), LOG, format, inputs...)
 }
 
 // Warn / Warnf
@@ -126,10 +164,10 @@ func (b *BugFixes) Warn(inputs ...interface{}) string {
 	return b.Warnf(variadicFormat(inputs), inputs...)
 }
 func Warnf(format string, inputs ...interface{}) string {
-	return (&BugFixes{LocalOnly: false}).Warnf(format, inputs...)
+	return std.Warnf(format, inputs...)
 }
 func (b *BugFixes) Warnf(format string, inputs ...interface{}) string {
-	return b.logAt("warn", format, inputs...)
+	return b.logger().logAt(b.context(), WARN, format, inputs...)
 }
 
 // Fatal / Fatalf — always captures stack, panics.
@@ -138,22 +176,93 @@ func (b *BugFixes) Fatal(inputs ...interface{}) {
 	b.Fatalf(variadicFormat(inputs), inputs...)
 }
 func Fatalf(format string, inputs ...interface{}) {
-	(&BugFixes{LocalOnly: false}).Fatalf(format, inputs...)
+	std.Fatalf(format, inputs...)
 }
 
 func (b *BugFixes) Fatalf(format string, inputs ...interface{}) {
-	if b == nil {
-		Fatalf(format, inputs...)
-		return
-	}
+	b.logger().fatalf(b.context(), format, inputs...)
+}
+
+// ErrorCtx / ErrorfCtx
+func ErrorCtx(ctx context.Context, inputs ...interface{}) error {
+	return ErrorfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func (b *BugFixes) ErrorCtx(ctx context.Context, inputs ...interface{}) error {
+	return b.ErrorfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func ErrorfCtx(ctx context.Context, format string, inputs ...interface{}) error {
+	return std.ErrorfCtx(ctx, format, inputs...)
+}
+func (b *BugFixes) ErrorfCtx(ctx context.Context, format string, inputs ...interface{}) error {
+	return b.logger().errorf(ctx, format, inputs...)
+}
 
-	b.Level = "fatal"
-	b.FormattedLog = fmt.Sprintf(format, inputs...)
-	b.Stack = debug.Stack()
+// InfoCtx / InfofCtx
+func InfoCtx(ctx context.Context, inputs ...interface{}) string {
+	return InfofCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func (b *BugFixes) InfoCtx(ctx context.Context, inputs ...interface{}) string {
+	return b.InfofCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func InfofCtx(ctx context.Context, format string, inputs ...interface{}) string {
+	return std.InfofCtx(ctx, format, inputs...)
+}
+func (b *BugFixes) InfofCtx(ctx context.Context, format string, inputs ...interface{}) string {
+	return b.logger().logAt(ctx, INFO, format, inputs...)
+}
 
-	if !b.LocalOnly {
-		b.DoReporting()
-	}
+// DebugCtx / DebugfCtx
+func DebugCtx(ctx context.Context, inputs ...interface{}) string {
+	return DebugfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func (b *BugFixes) DebugCtx(ctx context.Context, inputs ...interface{}) string {
+	return b.DebugfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func DebugfCtx(ctx context.Context, format string, inputs ...interface{}) string {
+	return std.DebugfCtx(ctx, format, inputs...)
+}
+func (b *BugFixes) DebugfCtx(ctx context.Context, format string, inputs ...interface{}) string {
+	return b.logger().logAt(ctx, DEBUG, format, inputs...)
+}
+
+// LogCtx / LogfCtx
+func LogCtx(ctx context.Context, inputs ...interface{}) string {
+	return LogfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func (b *BugFixes) LogCtx(ctx context.Context, inputs ...interface{}) string {
+	return b.LogfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func LogfCtx(ctx context.Context, format string, inputs ...interface{}) string {
+	return std.LogfCtx(ctx, format, inputs...)
+}
+func (b *BugFixes) LogfCtx(ctx context.Context, format string, inputs ...interface{}) string {
+	return b.logger().logAt(ctx, LOG, format, inputs...)
+}
 
-	panic(b)
+// WarnCtx / WarnfCtx
+func WarnCtx(ctx context.Context, inputs ...interface{}) string {
+	return WarnfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func (b *BugFixes) WarnCtx(ctx context.Context, inputs ...interface{}) string {
+	return b.WarnfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func WarnfCtx(ctx context.Context, format string, inputs ...interface{}) string {
+	return std.WarnfCtx(ctx, format, inputs...)
+}
+func (b *BugFixes) WarnfCtx(ctx context.Context, format string, inputs ...interface{}) string {
+	return b.logger().logAt(ctx, WARN, format, inputs...)
+}
+
+// FatalCtx / FatalfCtx
+func FatalCtx(ctx context.Context, inputs ...interface{}) {
+	FatalfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func (b *BugFixes) FatalCtx(ctx context.Context, inputs ...interface{}) {
+	b.FatalfCtx(ctx, variadicFormat(inputs), inputs...)
+}
+func FatalfCtx(ctx context.Context, format string, inputs ...interface{}) {
+	std.FatalfCtx(ctx, format, inputs...)
+}
+func (b *BugFixes) FatalfCtx(ctx context.Context, format string, inputs ...interface{}) {
+	b.logger().fatalf(ctx, format, inputs...)
 }
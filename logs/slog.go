@@ -0,0 +1,171 @@
+package logs
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"slices"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+// Handler is a slog.Handler that prints records like the package-level
+// functions and reports those at or above the configured level, with their
+// attributes and groups as structured fields.
+type Handler struct {
+	client *bugfixes.Client
+	fields map[string]any
+	groups []string
+}
+
+// NewHandler returns a Handler that reports through client. A nil client
+// means bugfixes.DefaultClient.
+func NewHandler(client *bugfixes.Client) *Handler {
+	if client == nil {
+		client = bugfixes.DefaultClient()
+	}
+
+	return &Handler{client: client}
+}
+
+// Enabled reports whether records at level are printed or reported.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	cfg := h.client.Config()
+	if cfg.LocalOnly || cfg.LogLevels != nil {
+		return true
+	}
+
+	reportLevel := ConvertLevelFromString(cfg.LogLevel)
+	return reportLevel == LevelUnknown || ConvertLevelFromString(slogLevel(level)) >= reportLevel
+}
+
+// Handle prints r and, if it is at or above the reporting level, reports it.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	fields := cloneFields(h.fields)
+	if r.NumAttrs() > 0 {
+		if fields == nil {
+			fields = make(map[string]any)
+		}
+		group := groupFields(fields, h.groups)
+		r.Attrs(func(a slog.Attr) bool {
+			addAttr(group, a)
+			return true
+		})
+		pruneEmptyGroups(fields)
+	}
+
+	b := &BugFixes{
+		Level:        slogLevel(r.Level),
+		FormattedLog: r.Message,
+		Fields:       fields,
+		Time:         r.Time,
+		client:       h.client,
+		ctx:          ctx,
+		pc:           r.PC,
+	}
+	if levelCapturesStack(b.Level) {
+		b.Stack = debug.Stack()
+	}
+	b.DoReporting()
+
+	return nil
+}
+
+// WithAttrs returns a Handler whose records carry attrs, inside any groups
+// opened with WithGroup.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	clone := *h
+	clone.fields = cloneFields(h.fields)
+	if clone.fields == nil {
+		clone.fields = make(map[string]any)
+	}
+	group := groupFields(clone.fields, h.groups)
+	for _, a := range attrs {
+		addAttr(group, a)
+	}
+	pruneEmptyGroups(clone.fields)
+
+	return &clone
+}
+
+// WithGroup returns a Handler that nests later attributes under name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := *h
+	clone.groups = append(slices.Clip(h.groups), name)
+
+	return &clone
+}
+
+// slogLevel maps a slog level to the nearest Bugfixes level name.
+func slogLevel(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return DEBUG
+	case level < slog.LevelWarn:
+		return INFO
+	case level < slog.LevelError:
+		return WARN
+	default:
+		return ERROR
+	}
+}
+
+// groupFields returns the map for the nested groups in fields, creating it
+// if needed.
+func groupFields(fields map[string]any, groups []string) map[string]any {
+	for _, name := range groups {
+		group, ok := fields[name].(map[string]any)
+		if !ok {
+			group = make(map[string]any)
+			fields[name] = group
+		}
+		fields = group
+	}
+
+	return fields
+}
+
+func addAttr(fields map[string]any, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		fields[a.Key] = fieldValue(a.Value.Any())
+		return
+	}
+
+	attrs := a.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+	if a.Key != "" {
+		fields = groupFields(fields, []string{a.Key})
+	}
+	for _, nested := range attrs {
+		addAttr(fields, nested)
+	}
+}
+
+// pruneEmptyGroups removes groups that ended up with no attributes.
+func pruneEmptyGroups(fields map[string]any) {
+	for key, value := range fields {
+		group, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		pruneEmptyGroups(group)
+		if len(group) == 0 {
+			delete(fields, key)
+		}
+	}
+}
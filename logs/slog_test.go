@@ -0,0 +1,101 @@
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func TestHandlerPassesSlogtest(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	client := bugfixes.NewClient(bugfixes.Config{Transport: recorder, LogLevel: DEBUG, QueueWorkers: 1})
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	results := func() []map[string]any {
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+
+		var entries []map[string]any
+		for _, event := range recorder.Events() {
+			var payload struct {
+				Log    string         `json:"log"`
+				Level  string         `json:"level"`
+				Time   *string        `json:"time"`
+				Fields map[string]any `json:"fields"`
+			}
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				t.Fatalf("decode %s: %v", event.Payload, err)
+			}
+
+			entry := map[string]any{
+				slog.MessageKey: payload.Log,
+				slog.LevelKey:   payload.Level,
+			}
+			if payload.Time != nil {
+				entry[slog.TimeKey] = *payload.Time
+			}
+			for key, value := range payload.Fields {
+				entry[key] = value
+			}
+			entries = append(entries, entry)
+		}
+
+		return entries
+	}
+
+	_, _ = captureStandardStreams(t, func() {
+		if err := slogtest.TestHandler(NewHandler(client), results); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestHandlerPrintsFieldsLocally(t *testing.T) {
+	client := bugfixes.NewClient(bugfixes.Config{LocalOnly: true})
+	logger := slog.New(NewHandler(client)).With("tenant", "acme").WithGroup("req")
+
+	stdout, _ := captureStandardStreams(t, func() {
+		logger.Info("served", "status", 200)
+	})
+
+	if !strings.Contains(stdout, "served req.status=200 tenant=acme") {
+		t.Fatalf("expected the message with its fields, got %q", stdout)
+	}
+	if !strings.Contains(stdout, "slog_test.go") {
+		t.Fatalf("expected the slog caller's file, got %q", stdout)
+	}
+}
+
+func TestHandlerReportsAtConfiguredLevel(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	client := bugfixes.NewClient(bugfixes.Config{Transport: recorder, LogLevel: WARN})
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+	handler := NewHandler(client)
+
+	if handler.Enabled(context.Background(), slog.LevelInfo) {
+		t.Fatal("expected info to be disabled below the warn level")
+	}
+
+	logger := slog.New(handler)
+	_, _ = captureStandardStreams(t, func() {
+		logger.Info("quiet")
+		logger.Error("loud", "err", context.Canceled)
+	})
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	events := recorder.Events()
+	if len(events) != 1 || events[0].Level != ERROR {
+		t.Fatalf("expected only the error to be reported, got %d events", len(events))
+	}
+	if !strings.Contains(string(events[0].Payload), `"fields":{"err":"context canceled"}`) {
+		t.Fatalf("expected the error attribute as a field, got %s", events[0].Payload)
+	}
+}
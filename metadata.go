@@ -0,0 +1,84 @@
+package bugfixes
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// EventContext says what produced an event. It is sent as the context
+// object of every report.
+type EventContext struct {
+	Service     string `json:"service,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Release     string `json:"release,omitempty"`
+
+	Hostname  string `json:"hostname,omitempty"`
+	PID       int    `json:"pid"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	GoVersion string `json:"go_version"`
+}
+
+// hostContext is collected once, at startup.
+var hostContext = newHostContext()
+
+func newHostContext() EventContext {
+	hostname, _ := os.Hostname()
+
+	return EventContext{
+		Hostname:  hostname,
+		PID:       os.Getpid(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// DefaultRelease is the release used when Config.Release is empty: the main
+// module's version, or for development builds the VCS revision, suffixed
+// with "-dirty" when the tree had local changes. It is empty if the binary
+// carries no build information.
+func DefaultRelease() string {
+	return defaultRelease()
+}
+
+var defaultRelease = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if version := info.Main.Version; version != "" && version != "(devel)" {
+		return version
+	}
+
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+
+	return revision
+})
+
+// eventContext returns the metadata c attaches to events.
+func (c Config) eventContext() EventContext {
+	ec := hostContext
+	ec.Service = c.Service
+	ec.Environment = c.Environment
+	ec.Release = c.Release
+	if ec.Release == "" {
+		ec.Release = DefaultRelease()
+	}
+
+	return ec
+}
@@ -0,0 +1,82 @@
+package bugfixes_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"runtime"
+	"testing"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func TestSubmitAttachesEventContext(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	recorder := bugfixes.NewRecorder()
+	cfg := bugfixes.Config{
+		Service:     "checkout",
+		Environment: "staging",
+		Release:     "v1.2.3",
+		Destinations: []bugfixes.Destination{
+			{Name: uniqueKey(t), Transport: recorder},
+		},
+	}
+
+	submitLevel(t, cfg, bugfixes.ERROR)
+	if err := bugfixes.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	events := recorder.Events()
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %d", len(events))
+	}
+	body, err := events[0].Body()
+	if err != nil {
+		t.Fatalf("body: %v", err)
+	}
+
+	var decoded struct {
+		Context bugfixes.EventContext `json:"context"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+
+	hostname, _ := os.Hostname()
+	expected := bugfixes.EventContext{
+		Service:     "checkout",
+		Environment: "staging",
+		Release:     "v1.2.3",
+		Hostname:    hostname,
+		PID:         os.Getpid(),
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		GoVersion:   runtime.Version(),
+	}
+	if decoded.Context != expected {
+		t.Fatalf("expected context %+v, got %+v", expected, decoded.Context)
+	}
+}
+
+func TestReleaseDefaultsFromBuildInfo(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	recorder := bugfixes.NewRecorder()
+	cfg := bugfixes.Config{Transport: recorder}
+
+	event := bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))
+	event.Level = bugfixes.ERROR
+	if _, err := bugfixes.ReportContext(context.Background(), cfg, event); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	events := recorder.Events()
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %d", len(events))
+	}
+	if got := events[0].Context.Release; got != bugfixes.DefaultRelease() {
+		t.Fatalf("expected release %q, got %q", bugfixes.DefaultRelease(), got)
+	}
+}
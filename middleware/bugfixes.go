@@ -1,12 +1,11 @@
 package middleware
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"runtime/debug"
 	"strconv"
 	"strings"
@@ -45,75 +44,91 @@ func (k *contextKey) String() string {
 }
 
 func SendToBugfixes(rvr interface{}) {
+	SendToBugfixesCtx(context.Background(), rvr)
+}
+
+// SendToBugfixesCtx is SendToBugfixes with the request ID, trace ID and tags
+// in ctx attached to the report.
+func SendToBugfixesCtx(ctx context.Context, rvr interface{}) {
 	cfg := bugfixes.GetDefaultConfig()
-	if cfg.AgentKey == "" || cfg.AgentSecret == "" {
+	if cfg.CheckCredentials() != nil {
 		return
 	}
 
 	s := &System{
 		Config: &cfg,
 	}
-	s.SendToBugfixes(rvr)
+	s.SendToBugfixesCtx(ctx, rvr)
 }
 
 func (s *System) SendToBugfixes(rvr interface{}) {
-	stack := debug.Stack()
-	go s.sendToBugfixes(rvr, stack)
+	s.reportPanic(context.Background(), rvr)
 }
 
-func (s *System) sendToBugfixes(rvr interface{}, debugStack []byte) {
+// SendToBugfixesCtx is SendToBugfixes with the request ID, trace ID and tags
+// in ctx attached to the report. Delivery happens in the background and is
+// not cancelled with ctx.
+func (s *System) SendToBugfixesCtx(ctx context.Context, rvr interface{}) {
+	s.reportPanic(ctx, rvr)
+}
+
+// reportPanic queues a bug report for rvr and returns its event ID, or ""
+// if nothing was queued.
+func (s *System) reportPanic(ctx context.Context, rvr interface{}) string {
 	cfg := s.config()
-	p := prettyStack{}
-	bug, err := p.bugParse(debugStack, rvr)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "bugfixes: failed to parse bug: %v\n", err)
-		return
+	if cfg.CheckCredentials() != nil {
+		return ""
 	}
 
-	body, err := json.Marshal(bug)
+	stack := debug.Stack()
+	p := prettyStack{}
+	bug, err := p.bugParse(stack, rvr)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "bugfixes: failed to marshall bug: %v\n", err)
-		return
+		bugfixes.ReportInternalError(cfg, fmt.Errorf("%w: parse bug: %w", bugfixes.ErrMarshal, err))
+		return ""
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), bugfixes.DefaultTimeout)
-	defer cancel()
-
-	request, err := http.NewRequestWithContext(ctx, "POST", cfg.BugEndpoint(), bytes.NewBuffer(body))
+	body, err := json.Marshal(bug)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "bugfixes: failed to new request: %v\n", err)
-		return
+		bugfixes.ReportInternalError(cfg, fmt.Errorf("%w: bug: %w", bugfixes.ErrMarshal, err))
+		return ""
 	}
-	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set("X-API-KEY", cfg.AgentKey)
-	request.Header.Set("X-API-SECRET", cfg.AgentSecret)
 
-	client := cfg.GetHTTPClient()
-	resp, err := client.Do(request)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "bugfixes: failed to send bug: %v\n", err)
-		return
-	}
-	if resp != nil && resp.Body != nil {
-		if err := resp.Body.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "bugfixes: failed to close body: %v\n", err)
+	event := bugfixes.NewEvent(bugfixes.KindBug, body)
+	event.Level = bugfixes.PANIC
+	event.Message = fmt.Sprint(rvr)
+	event.Stack = stack
+	event = event.WithContext(ctx)
+	if err := bugfixes.Submit(cfg, event); err != nil {
+		if !errors.Is(err, bugfixes.ErrDropped) {
+			bugfixes.ReportInternalError(cfg, err)
 		}
+		return ""
 	}
+
+	return event.ID
 }
 
 func (s *System) config() bugfixes.Config {
+	if s == nil {
+		return bugfixes.GetDefaultConfig()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	cfg := bugfixes.GetDefaultConfig()
-	if s != nil && s.Config != nil {
-		cfg = cfg.Merge(*s.Config)
+	if s.Client != nil {
+		cfg = s.Client.Config()
 	}
-	if s != nil {
-		cfg = cfg.Merge(bugfixes.Config{
-			AgentKey:    s.AgentID,
-			AgentSecret: s.Secret,
-		})
+	if s.Config != nil {
+		cfg = cfg.Merge(*s.Config)
 	}
 
-	return cfg
+	return cfg.Merge(bugfixes.Config{
+		AgentKey:    s.AgentID,
+		AgentSecret: s.Secret,
+	})
 }
 
 func ParseBugLine(bugLine string) (string, string, int, error) {
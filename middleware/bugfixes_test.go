@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -63,12 +62,11 @@ func TestParseBugLine(t *testing.T) {
 
 func TestSendToBugfixes(t *testing.T) {
 	t.Cleanup(bugfixes.ResetDefaultConfig)
-	if err := os.Setenv("BUGFIXES_AGENT_KEY", "test_key"); err != nil {
-		t.Fatalf("Could not set environment variable: %v", err)
-	}
-	if err := os.Setenv("BUGFIXES_AGENT_SECRET", "test_secret"); err != nil {
-		t.Fatalf("Could not set environment variable: %v", err)
-	}
+	// Scoped to this test: credentials left in the environment make later
+	// tests send to the default server, and those failures open the
+	// circuit breaker the recorder-backed tests share.
+	t.Setenv("BUGFIXES_AGENT_KEY", "test_key")
+	t.Setenv("BUGFIXES_AGENT_SECRET", "test_secret")
 
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
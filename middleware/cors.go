@@ -12,12 +12,28 @@ func (s *System) AddAllowedOrigins(origins ...string) {
 	s.AllowedOrigins = append(s.AllowedOrigins, origins...)
 }
 
+// SetAllowedOrigins replaces the allowed origins, e.g. after a config reload.
+func (s *System) SetAllowedOrigins(origins ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.AllowedOrigins = append([]string{}, origins...)
+}
+
 // Headers
 func (s *System) AddAllowedHeaders(headers ...string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.AllowedHeaders = append(s.AllowedHeaders, headers...)
 }
+
+// SetAllowedHeaders replaces the allowed headers. Accept and Content-Type are
+// always allowed.
+func (s *System) SetAllowedHeaders(headers ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.AllowedHeaders = append([]string{}, headers...)
+}
+
 func (s *System) getAllowedHeaders() string {
 	standardAllowed := []string{
 		"Accept",
@@ -35,6 +51,14 @@ func (s *System) AddAllowedMethods(methods ...string) {
 	defer s.mu.Unlock()
 	s.AllowedMethods = append(s.AllowedMethods, methods...)
 }
+
+// SetAllowedMethods replaces the allowed methods.
+func (s *System) SetAllowedMethods(methods ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.AllowedMethods = append([]string{}, methods...)
+}
+
 func (s *System) getAllowedMethods() string {
 	return strings.Join(s.AllowedMethods, ", ")
 }
@@ -59,26 +83,33 @@ func (s *System) CORS(next http.Handler) http.Handler {
 			return
 		}
 
-		isAllowed := s.wildcardEnabled()
+		// Read the settings together so a concurrent Set* call cannot mix
+		// old and new values within one response.
+		s.mu.RLock()
+		wildcard := s.wildcardEnabled()
+		isAllowed := wildcard
 		for _, origin := range s.AllowedOrigins {
 			if origin == originalOrigin {
 				isAllowed = true
 				break
 			}
 		}
+		allowedMethods := s.getAllowedMethods()
+		allowedHeaders := s.getAllowedHeaders()
+		s.mu.RUnlock()
 
 		if !isAllowed {
 			w.WriteHeader(http.StatusForbidden)
 			return
 		}
 
-		if s.wildcardEnabled() {
+		if wildcard {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 		} else {
 			w.Header().Set("Access-Control-Allow-Origin", originalOrigin)
 		}
-		w.Header().Set("Access-Control-Allow-Methods", s.getAllowedMethods())
-		w.Header().Set("Access-Control-Allow-Headers", s.getAllowedHeaders())
+		w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+		w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
 		w.Header().Set("Access-Control-Max-Age", "86400")
 		w.Header().Set("Vary", "Origin")
 
@@ -215,3 +215,35 @@ func TestAddAllowedHeaders_AppendsMultiple(t *testing.T) {
 
 	require.Len(t, s.AllowedHeaders, 3)
 }
+
+func TestCORS_SetAllowedOriginsReplacesWhileServing(t *testing.T) {
+	s := middleware.NewMiddleware()
+	s.AddAllowedOrigins("https://old.example")
+	handler := s.CORS(newOKHandler())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Origin", "https://old.example")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}()
+	s.SetAllowedOrigins("https://new.example")
+	s.SetAllowedMethods(http.MethodGet)
+	<-done
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://old.example")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://new.example")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "GET", rr.Header().Get("Access-Control-Allow-Methods"))
+}
@@ -7,7 +7,10 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"sync"
 	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
 )
 
 var (
@@ -32,6 +35,7 @@ const (
 )
 
 type LoggerSystem struct {
+	mu       sync.RWMutex
 	LogLevel Level
 }
 
@@ -63,16 +67,54 @@ func Logger(next http.Handler) http.Handler {
 	return DefaultLogger(next)
 }
 
+// SetLogLevel changes the level of requests logged from now on.
+func (s *LoggerSystem) SetLogLevel(logLevel Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.LogLevel = logLevel
+}
+
+// Reload takes the log level from cfg, so it can be passed to
+// bugfixes.Watcher.OnChange. A config without a LogLevel leaves it alone.
+func (s *LoggerSystem) Reload(cfg bugfixes.Config) {
+	if cfg.LogLevel == "" {
+		return
+	}
+	s.SetLogLevel(Level(bugfixes.ConvertLevelFromString(cfg.LogLevel)))
+}
+
+func (s *LoggerSystem) logLevel() Level {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.LogLevel
+}
+
 func (s *LoggerSystem) Logger(next http.Handler) http.Handler {
-	l := RequestLogger(&DefaultLogFormatter{
-		Logger:   log.New(os.Stdout, "", log.LstdFlags),
-		NoColor:  false,
-		LogLevel: s.LogLevel,
+	l := RequestLogger(&loggerSystemFormatter{
+		system: s,
+		logger: log.New(os.Stdout, "", log.LstdFlags),
 	})
 
 	return l(next)
 }
 
+// loggerSystemFormatter reads the LoggerSystem's level for each request, so
+// SetLogLevel takes effect without rebuilding the handler.
+type loggerSystemFormatter struct {
+	system *LoggerSystem
+	logger LoggerInterface
+}
+
+func (f *loggerSystemFormatter) NewLogEntry(r *http.Request) LogEntry {
+	formatter := &DefaultLogFormatter{
+		Logger:   f.logger,
+		NoColor:  false,
+		LogLevel: f.system.logLevel(),
+	}
+
+	return formatter.NewLogEntry(r)
+}
+
 // RequestLogger returns a logger handler using a custom LogFormatter.
 func RequestLogger(f LogFormatter) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -1,15 +1,19 @@
 package middleware_test
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"regexp"
 	"strings"
 	"sync"
 	"testing"
 
+	bugfixes "github.com/bugfixes/go-bugfixes"
 	"github.com/bugfixes/go-bugfixes/middleware"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // capturingLogger captures log output for assertions.
@@ -254,3 +258,28 @@ func levelName(l middleware.Level) string {
 		return "Unknown"
 	}
 }
+
+func TestLoggerSystem_ReloadChangesLevel(t *testing.T) {
+	origStdout := os.Stdout
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = writer
+	defer func() {
+		os.Stdout = origStdout
+	}()
+
+	ls := middleware.SetupLogger(middleware.Fatal)
+	handler := ls.Logger(handlerWithStatus(http.StatusNotFound))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/before", nil))
+	ls.Reload(bugfixes.Config{LogLevel: bugfixes.ERROR})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/after", nil))
+
+	_ = writer.Close()
+	output, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	assert.Equal(t, middleware.Error, ls.LogLevel)
+	assert.NotContains(t, string(output), "/before")
+	assert.Contains(t, string(output), "/after")
+}
@@ -45,6 +45,10 @@ type System struct {
 	Secret  string
 	Config  *bugfixes.Config
 
+	// Client reports panics. Nil means bugfixes.DefaultClient; Config,
+	// AgentID and Secret are applied over its config.
+	Client *bugfixes.Client
+
 	// Middlewares to use
 	Middlewares []func(handler http.Handler) http.Handler
 
@@ -62,6 +66,17 @@ func NewMiddleware() *System {
 	return &System{}
 }
 
+// NewMiddlewareWithClient returns a System that reports through client.
+func NewMiddlewareWithClient(client *bugfixes.Client) *System {
+	return &System{Client: client}
+}
+
+func (s *System) SetClient(client *bugfixes.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Client = client
+}
+
 func (s *System) SetupBugfixes(id, secret string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
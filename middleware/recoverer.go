@@ -11,9 +11,13 @@ import (
 	"unicode/utf8"
 )
 
+// HeaderEventID carries the ID of the bug report sent for a recovered panic.
+const HeaderEventID = "X-Bugfixes-Event-Id"
+
 // Recoverer is a middleware that recovers from panics, logs the panic (and a
 // backtrace), and returns an HTTP 500 (Internal Server Error) status if
-// possible. Recoverer prints a request ID if one is provided.
+// possible. Recoverer prints a request ID if one is provided, and sets
+// HeaderEventID on the response when the panic is reported.
 //
 // Alternatively, look at https://github.com/pressly/lg middleware pkgs.
 func Recoverer(next http.Handler) http.Handler {
@@ -34,9 +38,10 @@ func (s *System) Recoverer(next http.Handler) http.Handler {
 					PrintPrettyStack(rvr)
 				}
 
+				if eventID := s.reportPanic(r.Context(), rvr); eventID != "" {
+					w.Header().Set(HeaderEventID, eventID)
+				}
 				w.WriteHeader(http.StatusInternalServerError)
-
-				s.SendToBugfixes(rvr)
 			}
 		}()
 
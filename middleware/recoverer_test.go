@@ -1,14 +1,18 @@
 package middleware_test
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
+	bugfixes "github.com/bugfixes/go-bugfixes"
 	"github.com/bugfixes/go-bugfixes/middleware"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func captureStderr(t *testing.T, fn func()) string {
@@ -148,6 +152,102 @@ func TestRecoverer_SystemMethod(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
 }
 
+func TestRecoverer_SendsToConfiguredTransport(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+
+	s := middleware.NewMiddleware()
+	s.SetConfig(bugfixes.Config{Transport: recorder})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("transport recoverer test")
+	})
+	handler := s.Recoverer(next)
+
+	rec := httptest.NewRecorder()
+	_ = captureStderr(t, func() {
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	require.NoError(t, bugfixes.Flush(context.Background()))
+
+	events := recorder.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, bugfixes.KindBug, events[0].Kind)
+	assert.Contains(t, string(events[0].Payload), "recoverer_test.go")
+	assert.NotEmpty(t, events[0].ID)
+	assert.Equal(t, events[0].ID, rec.Header().Get(middleware.HeaderEventID))
+}
+
+func TestRecoverer_ReportsRequestID(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+
+	s := middleware.NewMiddleware()
+	s.SetConfig(bugfixes.Config{Transport: recorder})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("request id recoverer test")
+	})
+	handler := middleware.RequestID(s.Recoverer(next))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-recoverer")
+	_ = captureStderr(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	})
+	require.NoError(t, bugfixes.Flush(context.Background()))
+
+	events := recorder.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "req-recoverer", events[0].RequestID)
+}
+
+func TestRecoverer_ReportsThroughClient(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	client := bugfixes.NewClient(bugfixes.Config{Transport: recorder})
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	s := middleware.NewMiddlewareWithClient(client)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("client recoverer test")
+	})
+	handler := s.Recoverer(next)
+
+	_ = captureStderr(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	require.NoError(t, client.Flush(context.Background()))
+
+	events := recorder.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, bugfixes.KindBug, events[0].Kind)
+}
+
+func TestRecoverer_NoEventIDForDroppedReport(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	client := bugfixes.NewClient(bugfixes.Config{Transport: recorder, DedupWindow: time.Minute})
+
+	s := middleware.NewMiddlewareWithClient(client)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("deduplicated recoverer test")
+	})
+	handler := s.Recoverer(next)
+
+	var ids []string
+	_ = captureStderr(t, func() {
+		for range 2 {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			ids = append(ids, rec.Header().Get(middleware.HeaderEventID))
+		}
+	})
+	require.NoError(t, client.Flush(context.Background()))
+
+	events := recorder.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, events[0].ID, ids[0])
+	assert.Empty(t, ids[1], "a repeat held back by deduplication has no event to look up")
+	require.NoError(t, client.Close(context.Background()))
+}
+
 func TestPrintPrettyStack_StackBytesHideRawByteSlice(t *testing.T) {
 	fakeStack := []byte(`goroutine 1 [running]:
 runtime/debug.Stack()
@@ -9,13 +9,14 @@ import (
 	"os"
 	"strings"
 	"sync/atomic"
-)
 
-// Key to use when setting the request ID.
-type ctxKeyRequestID int
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
 
-// RequestIDKey is the key that holds the unique request ID in a request context.
-const RequestIDKey ctxKeyRequestID = 0
+// RequestIDKey is the key that holds the unique request ID in a request
+// context. It is shared with the bugfixes package, so reports made with a
+// request context carry the request ID.
+const RequestIDKey = bugfixes.RequestIDKey
 
 // RequestIDHeader is the name of the HTTP Header which contains the request id.
 // Exported so that it can be changed by developers
@@ -0,0 +1,257 @@
+package bugfixes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const DefaultQueueSize = 1000
+
+const DefaultQueueWorkers = 4
+
+// DropPolicy decides which report is discarded when the queue is full.
+type DropPolicy int
+
+const (
+	// DropNewest rejects the report being enqueued.
+	DropNewest DropPolicy = iota
+	// DropOldest evicts the longest-waiting report to make room.
+	DropOldest
+)
+
+// UnmarshalText parses "newest" or "oldest", so a DropPolicy can be set from
+// a configuration file.
+func (p *DropPolicy) UnmarshalText(text []byte) error {
+	switch strings.ToLower(strings.TrimSpace(string(text))) {
+	case "newest":
+		*p = DropNewest
+	case "oldest":
+		*p = DropOldest
+	default:
+		return fmt.Errorf("unknown drop policy %q", text)
+	}
+
+	return nil
+}
+
+var (
+	ErrQueueFull   = errors.New("bugfixes: queue full")
+	ErrQueueClosed = errors.New("bugfixes: queue closed")
+)
+
+// Job is a unit of delivery work run by a queue worker. The context is
+// cancelled if the queue is closed before the job finishes.
+type Job func(ctx context.Context)
+
+// Queue is a bounded in-memory delivery queue drained by a fixed pool of
+// workers.
+type Queue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	jobs    []Job
+	size    int
+	policy  DropPolicy
+	pending int
+	idle    chan struct{}
+	closed  bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	dropped atomic.Uint64
+	onDrop  func(reason string, n uint64)
+}
+
+// NewQueue starts a queue holding at most size jobs, drained by workers
+// goroutines. Non-positive values fall back to the defaults.
+func NewQueue(size, workers int, policy DropPolicy) *Queue {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	if workers <= 0 {
+		workers = DefaultQueueWorkers
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		size:   size,
+		policy: policy,
+		idle:   closedChan(),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	q.wg.Add(workers)
+	for range workers {
+		go q.work()
+	}
+
+	return q
+}
+
+// Enqueue adds a job to the queue. It returns ErrQueueClosed after Close,
+// and ErrQueueFull if the queue is full and the policy is DropNewest.
+func (q *Queue) Enqueue(job Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		q.drop(DropQueueClosed, 1)
+		return ErrQueueClosed
+	}
+
+	if len(q.jobs) >= q.size {
+		q.drop(DropQueueFull, 1)
+		if q.policy != DropOldest {
+			return ErrQueueFull
+		}
+		q.jobs[0] = nil
+		q.jobs = append(q.jobs[1:], job)
+		return nil
+	}
+
+	q.jobs = append(q.jobs, job)
+	if q.pending == 0 {
+		q.idle = make(chan struct{})
+	}
+	q.pending++
+	q.cond.Signal()
+
+	return nil
+}
+
+// Len returns the number of jobs waiting to be picked up by a worker.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// Dropped returns the number of jobs discarded because the queue was full
+// or closed.
+func (q *Queue) Dropped() uint64 {
+	return q.dropped.Load()
+}
+
+// Flush blocks until every queued and in-flight job has finished, or ctx
+// is done.
+func (q *Queue) Flush(ctx context.Context) error {
+	q.mu.Lock()
+	idle := q.idle
+	q.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting new jobs and waits for pending ones to drain. If ctx
+// ends first, in-flight jobs are cancelled, anything still queued is
+// discarded, and ctx's error is returned.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		if len(q.jobs) > 0 {
+			q.drop(DropQueueClosed, uint64(len(q.jobs)))
+			q.pending -= len(q.jobs)
+			q.jobs = nil
+			if q.pending == 0 {
+				close(q.idle)
+			}
+		}
+		q.mu.Unlock()
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) drop(reason string, n uint64) {
+	q.dropped.Add(n)
+	if q.onDrop != nil {
+		q.onDrop(reason, n)
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	for {
+		q.mu.Lock()
+		for len(q.jobs) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.jobs) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		job := q.jobs[0]
+		q.jobs[0] = nil
+		q.jobs = q.jobs[1:]
+		q.mu.Unlock()
+
+		job(q.ctx)
+
+		q.mu.Lock()
+		q.pending--
+		if q.pending == 0 {
+			close(q.idle)
+		}
+		q.mu.Unlock()
+	}
+}
+
+func closedChan() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}
+
+// DefaultQueue returns the delivery queue of DefaultClient, starting it on
+// first use.
+func DefaultQueue() *Queue {
+	return defaultClient.Queue()
+}
+
+// Enqueue adds a job to the default queue.
+func Enqueue(job Job) error {
+	return DefaultQueue().Enqueue(job)
+}
+
+// Flush waits for the queues of DefaultClient to drain.
+func Flush(ctx context.Context) error {
+	return defaultClient.Flush(ctx)
+}
+
+// Close drains and stops the queues of DefaultClient, then seals the spools
+// only it writes to. Spools of clients made with NewClient keep replaying
+// until those clients are closed.
+// Reports made afterwards start a fresh queue, so call it as late as possible
+// during shutdown.
+func Close(ctx context.Context) error {
+	return defaultClient.Close(ctx)
+}
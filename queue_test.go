@@ -0,0 +1,148 @@
+package bugfixes_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func TestQueueRunsJobsWithBoundedWorkers(t *testing.T) {
+	q := bugfixes.NewQueue(100, 2, bugfixes.DropNewest)
+	t.Cleanup(func() { _ = q.Close(context.Background()) })
+
+	var running, peak, done atomic.Int32
+	for range 20 {
+		err := q.Enqueue(func(context.Context) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			done.Add(1)
+		})
+		if err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	if err := q.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if done.Load() != 20 {
+		t.Fatalf("expected 20 jobs to run, got %d", done.Load())
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("expected at most 2 concurrent jobs, got %d", peak.Load())
+	}
+}
+
+func TestQueueDropPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy bugfixes.DropPolicy
+		expect []int
+	}{
+		{name: "drop newest", policy: bugfixes.DropNewest, expect: []int{0, 1}},
+		{name: "drop oldest", policy: bugfixes.DropOldest, expect: []int{2, 3}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q := bugfixes.NewQueue(2, 1, test.policy)
+			t.Cleanup(func() { _ = q.Close(context.Background()) })
+
+			release := make(chan struct{})
+			started := make(chan struct{})
+			if err := q.Enqueue(func(context.Context) {
+				close(started)
+				<-release
+			}); err != nil {
+				t.Fatalf("enqueue blocker: %v", err)
+			}
+			<-started
+
+			var mu sync.Mutex
+			var ran []int
+			for i := range 4 {
+				err := q.Enqueue(func(context.Context) {
+					mu.Lock()
+					ran = append(ran, i)
+					mu.Unlock()
+				})
+				if test.policy == bugfixes.DropNewest && i >= 2 && !errors.Is(err, bugfixes.ErrQueueFull) {
+					t.Fatalf("expected ErrQueueFull for job %d, got %v", i, err)
+				}
+			}
+			if q.Dropped() != 2 {
+				t.Fatalf("expected 2 dropped jobs, got %d", q.Dropped())
+			}
+
+			close(release)
+			if err := q.Flush(context.Background()); err != nil {
+				t.Fatalf("flush: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(ran) != len(test.expect) || ran[0] != test.expect[0] || ran[1] != test.expect[1] {
+				t.Fatalf("expected jobs %v to run, got %v", test.expect, ran)
+			}
+		})
+	}
+}
+
+func TestQueueCloseDrainsAndRejects(t *testing.T) {
+	q := bugfixes.NewQueue(10, 1, bugfixes.DropNewest)
+
+	var done atomic.Int32
+	for range 5 {
+		_ = q.Enqueue(func(context.Context) {
+			time.Sleep(time.Millisecond)
+			done.Add(1)
+		})
+	}
+
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if done.Load() != 5 {
+		t.Fatalf("expected pending jobs to drain, got %d", done.Load())
+	}
+	if err := q.Enqueue(func(context.Context) {}); !errors.Is(err, bugfixes.ErrQueueClosed) {
+		t.Fatalf("expected ErrQueueClosed, got %v", err)
+	}
+}
+
+func TestQueueCloseDeadlineCancelsInFlight(t *testing.T) {
+	q := bugfixes.NewQueue(10, 1, bugfixes.DropNewest)
+
+	cancelled := make(chan struct{})
+	_ = q.Enqueue(func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+	_ = q.Enqueue(func(context.Context) {
+		t.Error("queued job should have been discarded")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := q.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	<-cancelled
+
+	if err := q.Flush(context.Background()); err != nil {
+		t.Fatalf("flush after close: %v", err)
+	}
+}
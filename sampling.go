@@ -0,0 +1,190 @@
+package bugfixes
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket: PerSecond tokens are added each second, up to
+// Burst. The zero value does not limit.
+type RateLimit struct {
+	PerSecond float64 `yaml:"per_second"`
+	Burst     int     `yaml:"burst"`
+}
+
+func (l RateLimit) enabled() bool {
+	return l.PerSecond > 0
+}
+
+func (l RateLimit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.PerSecond))
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket for the time since it was last used and spends a
+// token if one is available.
+func (b *tokenBucket) take(limit RateLimit, now time.Time) bool {
+	burst := limit.burst()
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.PerSecond)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sampler applies sample rates and rate limits, counting what it drops per
+// level so the next report at that level can carry the count.
+type sampler struct {
+	mu           sync.Mutex
+	levels       map[string]*tokenBucket
+	fingerprints map[string]*tokenBucket
+	dropped      map[string]int
+	lastPrune    time.Time
+}
+
+// admit reports whether event should be sent. Admitted events carry their
+// level's sample rate and the number of events at that level dropped since
+// the last one was admitted.
+func (s *sampler) admit(event *Event, cfg Config) bool {
+	rate, sampled := cfg.SampleRates[event.Level]
+	levelLimit := cfg.LevelRateLimits[event.Level]
+	if !sampled && !levelLimit.enabled() && !cfg.FingerprintRateLimit.enabled() {
+		return true
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.levels == nil {
+		s.levels = make(map[string]*tokenBucket)
+		s.fingerprints = make(map[string]*tokenBucket)
+		s.dropped = make(map[string]int)
+	}
+
+	reason := ""
+	switch {
+	case sampled && (rate <= 0 || (rate < 1 && rand.Float64() >= rate)):
+		reason = DropSampled
+	case levelLimit.enabled() && !bucket(s.levels, event.Level).take(levelLimit, now):
+		reason = DropRateLimited
+	case cfg.FingerprintRateLimit.enabled():
+		s.pruneFingerprints(cfg.FingerprintRateLimit, now)
+		if !bucket(s.fingerprints, event.Fingerprint).take(cfg.FingerprintRateLimit, now) {
+			reason = DropRateLimited
+		}
+	}
+
+	if reason != "" {
+		s.dropped[event.Level]++
+		statsFor(cfg).drop(reason, 1)
+		return false
+	}
+
+	if sampled {
+		event.SampleRate = rate
+	}
+	event.Dropped = s.dropped[event.Level]
+	delete(s.dropped, event.Level)
+
+	return true
+}
+
+// pruneFingerprints forgets buckets that have refilled completely, since a
+// new bucket starts full anyway.
+func (s *sampler) pruneFingerprints(limit RateLimit, now time.Time) {
+	full := time.Duration(limit.burst() / limit.PerSecond * float64(time.Second))
+	if now.Sub(s.lastPrune) < full {
+		return
+	}
+	for fingerprint, b := range s.fingerprints {
+		if now.Sub(b.last) >= full {
+			delete(s.fingerprints, fingerprint)
+		}
+	}
+	s.lastPrune = now
+}
+
+func bucket(buckets map[string]*tokenBucket, key string) *tokenBucket {
+	b, ok := buckets[key]
+	if !ok {
+		b = &tokenBucket{}
+		buckets[key] = b
+	}
+	return b
+}
+
+// ParseSampleRates reads a comma-separated list of level=rate pairs, such as
+// "error=1,info=0.1,debug=0.01".
+func ParseSampleRates(value string) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	for pair := range strings.SplitSeq(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		level, raw, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: sample rate %q is not level=rate", ErrInvalidConfig, pair)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("%w: sample rate %q must be between 0 and 1", ErrInvalidConfig, pair)
+		}
+		rates[strings.ToLower(strings.TrimSpace(level))] = rate
+	}
+
+	return rates, nil
+}
+
+// envSampleRatesCache holds the rates last parsed from the environment, so
+// they are parsed again only when the variable changes.
+var envSampleRatesCache struct {
+	sync.Mutex
+	name, raw string
+	rates     map[string]float64
+}
+
+func envSampleRates(name string) map[string]float64 {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return nil
+	}
+
+	envSampleRatesCache.Lock()
+	defer envSampleRatesCache.Unlock()
+	if envSampleRatesCache.name == name && envSampleRatesCache.raw == raw {
+		return envSampleRatesCache.rates
+	}
+
+	rates, err := ParseSampleRates(raw)
+	if err != nil {
+		ReportInternalError(Config{}, fmt.Errorf("%s: %w, sampling disabled", name, err))
+		rates = nil
+	}
+	envSampleRatesCache.name, envSampleRatesCache.raw, envSampleRatesCache.rates = name, raw, rates
+
+	return rates
+}
@@ -0,0 +1,116 @@
+package bugfixes_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+// uniqueKey keeps the shared sampler state of one run from affecting the
+// next.
+func uniqueKey(t *testing.T) string {
+	t.Helper()
+	return fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano())
+}
+
+func submitN(t *testing.T, cfg bugfixes.Config, level, message string, n int) {
+	t.Helper()
+
+	for range n {
+		event := bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))
+		event.Level = level
+		event.Message = message
+		if err := bugfixes.Submit(cfg, event); err != nil && !errors.Is(err, bugfixes.ErrDropped) {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+	if err := bugfixes.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+}
+
+func TestSubmitSamplesByLevel(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	recorder := bugfixes.NewRecorder()
+	level := t.Name()
+	cfg := bugfixes.Config{
+		Transport:   recorder,
+		SampleRates: map[string]float64{level: 0},
+	}
+
+	submitN(t, cfg, level, "noisy", 4)
+	if got := len(recorder.Events()); got != 0 {
+		t.Fatalf("expected every event sampled out, got %d", got)
+	}
+
+	cfg.SampleRates = map[string]float64{level: 1}
+	submitN(t, cfg, level, "noisy", 1)
+
+	events := recorder.Events()
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %d", len(events))
+	}
+	if events[0].Dropped != 4 || events[0].SampleRate != 1 {
+		t.Fatalf("expected the dropped count and rate to be reported, got dropped=%d rate=%v", events[0].Dropped, events[0].SampleRate)
+	}
+}
+
+func TestSubmitRateLimitsPerLevel(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	recorder := bugfixes.NewRecorder()
+	level := uniqueKey(t)
+	cfg := bugfixes.Config{
+		Transport: recorder,
+		LevelRateLimits: map[string]bugfixes.RateLimit{
+			level: {PerSecond: 0.001, Burst: 2},
+		},
+	}
+
+	submitN(t, cfg, level, "first", 3)
+	submitN(t, cfg, level, "second", 2)
+
+	if got := len(recorder.Events()); got != 2 {
+		t.Fatalf("expected the burst to be sent, got %d", got)
+	}
+}
+
+func TestSubmitRateLimitsPerFingerprint(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	recorder := bugfixes.NewRecorder()
+	cfg := bugfixes.Config{
+		Transport:            recorder,
+		FingerprintRateLimit: bugfixes.RateLimit{PerSecond: 0.001, Burst: 1},
+	}
+
+	// messages are normalized, so the level keeps runs apart
+	level := uniqueKey(t)
+	submitN(t, cfg, level, "connection reset", 3)
+	submitN(t, cfg, level, "disk full", 3)
+
+	if got := len(recorder.Events()); got != 2 {
+		t.Fatalf("expected one event per fingerprint, got %d", got)
+	}
+}
+
+func TestParseSampleRates(t *testing.T) {
+	rates, err := bugfixes.ParseSampleRates("error=1, Info=0.1,debug=0.01")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if rates["error"] != 1 || rates["info"] != 0.1 || rates["debug"] != 0.01 {
+		t.Fatalf("unexpected rates %v", rates)
+	}
+
+	for _, bad := range []string{"info", "info=x", "info=2"} {
+		if _, err := bugfixes.ParseSampleRates(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
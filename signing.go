@@ -0,0 +1,187 @@
+package bugfixes
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers used to authenticate requests to the Bugfixes API.
+const (
+	HeaderAPIKey    = "X-API-KEY"
+	HeaderAPISecret = "X-API-SECRET"
+	HeaderTimestamp = "X-API-TIMESTAMP"
+	HeaderNonce     = "X-API-NONCE"
+	HeaderSignature = "X-API-SIGNATURE"
+)
+
+// DefaultSignatureMaxSkew is how far a signed request's timestamp may drift
+// from the verifier's clock.
+const DefaultSignatureMaxSkew = 5 * time.Minute
+
+// DefaultSignatureMaxBodyBytes is the largest request body a Verifier reads.
+const DefaultSignatureMaxBodyBytes = 10 << 20
+
+var (
+	ErrMissingSignature = errors.New("bugfixes: request is not signed")
+	ErrUnknownKey       = errors.New("bugfixes: unknown agent key")
+	ErrInvalidSignature = errors.New("bugfixes: invalid request signature")
+	ErrStaleRequest     = errors.New("bugfixes: request timestamp outside allowed skew")
+	ErrReplayedRequest  = errors.New("bugfixes: request nonce already used")
+	ErrRequestTooLarge  = errors.New("bugfixes: request body too large")
+	// ErrSigning is returned by SignRequest when the request cannot be
+	// signed. Sends that fail with it are not retried.
+	ErrSigning = errors.New("bugfixes: failed to sign request")
+)
+
+// Signature computes the hex HMAC-SHA256 of a request using the agent
+// secret. The signed string is the method, escaped path, timestamp, nonce
+// and hex SHA-256 of the body, separated by newlines.
+func Signature(secret, method, path, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", method, path, timestamp, nonce, hex.EncodeToString(bodyHash[:]))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest adds the agent key, a timestamp, a random nonce and the
+// signature to r. body must be the exact bytes r will send. The secret
+// itself is never put on the wire.
+func SignRequest(r *http.Request, key, secret string, body []byte) error {
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return fmt.Errorf("%w: generate nonce: %w", ErrSigning, err)
+	}
+	nonce := hex.EncodeToString(raw[:])
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	r.Header.Del(HeaderAPISecret)
+	r.Header.Set(HeaderAPIKey, key)
+	r.Header.Set(HeaderTimestamp, timestamp)
+	r.Header.Set(HeaderNonce, nonce)
+	r.Header.Set(HeaderSignature, Signature(secret, r.Method, r.URL.EscapedPath(), timestamp, nonce, body))
+
+	return nil
+}
+
+// Verifier authenticates signed requests and rejects replays by remembering
+// every nonce seen within the allowed clock skew.
+type Verifier struct {
+	// Secret looks up the secret for an agent key.
+	Secret func(key string) (string, bool)
+	// MaxSkew bounds the difference between the request timestamp and now.
+	MaxSkew time.Duration
+	// MaxBodyBytes bounds the body read to check the signature. Zero means
+	// DefaultSignatureMaxBodyBytes.
+	MaxBodyBytes int64
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastPrune time.Time
+}
+
+func NewVerifier(secret func(key string) (string, bool)) *Verifier {
+	return &Verifier{
+		Secret:  secret,
+		MaxSkew: DefaultSignatureMaxSkew,
+	}
+}
+
+// Verify checks r's signature and returns the agent key it was signed with.
+// The timestamp is checked before the body is read, and bodies over
+// MaxBodyBytes are rejected with ErrRequestTooLarge. The body is read and
+// replaced, so handlers can still consume it.
+func (v *Verifier) Verify(r *http.Request) (string, error) {
+	key := r.Header.Get(HeaderAPIKey)
+	timestamp := r.Header.Get(HeaderTimestamp)
+	nonce := r.Header.Get(HeaderNonce)
+	signature := r.Header.Get(HeaderSignature)
+	if key == "" || timestamp == "" || nonce == "" || signature == "" {
+		return "", ErrMissingSignature
+	}
+
+	secret, ok := v.Secret(key)
+	if !ok {
+		return "", ErrUnknownKey
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+
+	maxSkew := v.MaxSkew
+	if maxSkew <= 0 {
+		maxSkew = DefaultSignatureMaxSkew
+	}
+	now := time.Now()
+	signedAt := time.Unix(seconds, 0)
+	if signedAt.Before(now.Add(-maxSkew)) || signedAt.After(now.Add(maxSkew)) {
+		return "", ErrStaleRequest
+	}
+
+	var body []byte
+	if r.Body != nil {
+		maxBytes := v.MaxBodyBytes
+		if maxBytes <= 0 {
+			maxBytes = DefaultSignatureMaxBodyBytes
+		}
+		body, err = io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+		_ = r.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("bugfixes: read request body: %w", err)
+		}
+		if int64(len(body)) > maxBytes {
+			return "", ErrRequestTooLarge
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := Signature(secret, r.Method, r.URL.EscapedPath(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", ErrInvalidSignature
+	}
+
+	if !v.remember(key+":"+nonce, now, signedAt.Add(maxSkew)) {
+		return "", ErrReplayedRequest
+	}
+
+	return key, nil
+}
+
+// remember records a nonce until expiry, returning false if it was already
+// seen.
+func (v *Verifier) remember(nonce string, now, expiry time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.nonces == nil {
+		v.nonces = make(map[string]time.Time)
+	}
+	if now.Sub(v.lastPrune) > time.Minute {
+		for seen, until := range v.nonces {
+			if now.After(until) {
+				delete(v.nonces, seen)
+			}
+		}
+		v.lastPrune = now
+	}
+
+	if until, ok := v.nonces[nonce]; ok && !now.After(until) {
+		return false
+	}
+	v.nonces[nonce] = expiry
+
+	return true
+}
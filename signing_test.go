@@ -0,0 +1,153 @@
+package bugfixes_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func testVerifier() *bugfixes.Verifier {
+	return bugfixes.NewVerifier(func(key string) (string, bool) {
+		if key == "key" {
+			return "secret", true
+		}
+		return "", false
+	})
+}
+
+func signedRequest(t *testing.T, body string) *http.Request {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/log", strings.NewReader(body))
+	if err := bugfixes.SignRequest(r, "key", "secret", []byte(body)); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	return r
+}
+
+func TestSignedTransportVerifies(t *testing.T) {
+	verifier := testVerifier()
+
+	var verifyErr error
+	var gotSecret, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, verifyErr = verifier.Verify(r)
+		gotSecret = r.Header.Get(bugfixes.HeaderAPISecret)
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	transport := bugfixes.NewHTTPTransport(bugfixes.Config{
+		Server:       server.URL + "/v1",
+		AgentKey:     "key",
+		AgentSecret:  "secret",
+		SignRequests: true,
+	})
+	event := bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"log":"x"}`))
+	if err := transport.Send(context.Background(), event); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if verifyErr != nil {
+		t.Fatalf("expected signature to verify, got %v", verifyErr)
+	}
+	if gotSecret != "" {
+		t.Fatalf("expected the secret not to be sent, got %q", gotSecret)
+	}
+	if gotBody != `{"event_id":"`+event.ID+`","log":"x"}` {
+		t.Fatalf("expected the body to remain readable, got %q", gotBody)
+	}
+}
+
+func TestVerifierRejectsReplay(t *testing.T) {
+	verifier := testVerifier()
+	r := signedRequest(t, `{}`)
+
+	replay := r.Clone(context.Background())
+	replay.Body = io.NopCloser(strings.NewReader(`{}`))
+
+	if _, err := verifier.Verify(r); err != nil {
+		t.Fatalf("first verify: %v", err)
+	}
+	if _, err := verifier.Verify(replay); !errors.Is(err, bugfixes.ErrReplayedRequest) {
+		t.Fatalf("expected ErrReplayedRequest, got %v", err)
+	}
+}
+
+func TestVerifierRejectsTamperedBody(t *testing.T) {
+	r := signedRequest(t, `{"level":"error"}`)
+	r.Body = io.NopCloser(strings.NewReader(`{"level":"info"}`))
+
+	if _, err := testVerifier().Verify(r); !errors.Is(err, bugfixes.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestVerifierRejectsStaleTimestamp(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/v1/log", strings.NewReader(`{}`))
+	timestamp := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	r.Header.Set(bugfixes.HeaderAPIKey, "key")
+	r.Header.Set(bugfixes.HeaderTimestamp, timestamp)
+	r.Header.Set(bugfixes.HeaderNonce, "nonce")
+	r.Header.Set(bugfixes.HeaderSignature, bugfixes.Signature("secret", http.MethodPost, "/v1/log", timestamp, "nonce", []byte(`{}`)))
+
+	if _, err := testVerifier().Verify(r); !errors.Is(err, bugfixes.ErrStaleRequest) {
+		t.Fatalf("expected ErrStaleRequest, got %v", err)
+	}
+}
+
+func TestVerifierRejectsUnsignedAndUnknown(t *testing.T) {
+	unsigned := httptest.NewRequest(http.MethodPost, "/v1/log", nil)
+	if _, err := testVerifier().Verify(unsigned); !errors.Is(err, bugfixes.ErrMissingSignature) {
+		t.Fatalf("expected ErrMissingSignature, got %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/log", nil)
+	if err := bugfixes.SignRequest(r, "other", "secret", nil); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if _, err := testVerifier().Verify(r); !errors.Is(err, bugfixes.ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+type unreadableBody struct{}
+
+func (unreadableBody) Read([]byte) (int, error) {
+	return 0, errors.New("body read before the timestamp was checked")
+}
+
+func TestVerifierChecksTimestampBeforeReadingBody(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/v1/log", unreadableBody{})
+	r.Header.Set(bugfixes.HeaderAPIKey, "key")
+	r.Header.Set(bugfixes.HeaderTimestamp, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	r.Header.Set(bugfixes.HeaderNonce, "nonce")
+	r.Header.Set(bugfixes.HeaderSignature, "signature")
+
+	if _, err := testVerifier().Verify(r); !errors.Is(err, bugfixes.ErrStaleRequest) {
+		t.Fatalf("expected ErrStaleRequest without reading the body, got %v", err)
+	}
+}
+
+func TestVerifierRejectsLargeBody(t *testing.T) {
+	verifier := testVerifier()
+	verifier.MaxBodyBytes = 16
+
+	if _, err := verifier.Verify(signedRequest(t, `{"n":1}`)); err != nil {
+		t.Fatalf("expected a small body to verify, got %v", err)
+	}
+	if _, err := verifier.Verify(signedRequest(t, strings.Repeat("x", 17))); !errors.Is(err, bugfixes.ErrRequestTooLarge) {
+		t.Fatalf("expected ErrRequestTooLarge, got %v", err)
+	}
+}
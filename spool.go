@@ -0,0 +1,705 @@
+package bugfixes
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultSpoolMaxBytes = 64 << 20
+
+const DefaultSpoolMaxAge = 24 * time.Hour
+
+const DefaultSpoolReplayInterval = 30 * time.Second
+
+// spoolSegmentMaxBytes is the size at which the active segment is sealed and
+// a new one started.
+const spoolSegmentMaxBytes = 1 << 20
+
+const (
+	spoolActiveExt = ".open"
+	spoolSealedExt = ".jsonl"
+	spoolTempExt   = ".tmp"
+)
+
+// SendFunc delivers a single spooled event.
+type SendFunc func(ctx context.Context, event Event) error
+
+// spoolRecord is a spooled event and the owner it is replayed for. Records
+// written by Append have no owner.
+type spoolRecord struct {
+	Event
+	Owner string `json:"spool_owner,omitempty"`
+}
+
+// recordSendFunc delivers a single spooled record.
+type recordSendFunc func(ctx context.Context, record spoolRecord) error
+
+// errNoSpoolOwner is returned by a recordSendFunc for a record whose owner
+// is not reporting in this process. The record is kept for later.
+var errNoSpoolOwner = errors.New("bugfixes: spool record owner not open")
+
+// Spool is an on-disk store for reports that could not be delivered. Records
+// are appended as JSON lines to an active segment, fsynced, and the segment
+// is sealed with an atomic rename once it is full or about to be replayed.
+type Spool struct {
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+
+	mu            sync.Mutex
+	active        *os.File
+	activeName    string
+	size          int64
+	seq           uint64
+	replayingName string
+
+	replayMu sync.Mutex
+
+	// onDrop is told of records discarded unsent, with the owner they were
+	// spooled for.
+	onDrop func(reason, owner string, n uint64)
+
+	kick chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// OpenSpool opens (creating if needed) a spool in dir. Segments left open by
+// a previous process are sealed so they can be replayed. Non-positive caps
+// fall back to the defaults.
+func OpenSpool(dir string, maxBytes int64, maxAge time.Duration) (*Spool, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultSpoolMaxBytes
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultSpoolMaxAge
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("bugfixes: create spool: %w", err)
+	}
+
+	s := &Spool{
+		dir:      dir,
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+		kick:     make(chan struct{}, 1),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("bugfixes: read spool: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		switch filepath.Ext(name) {
+		case spoolTempExt:
+			_ = os.Remove(filepath.Join(dir, name))
+		case spoolActiveExt:
+			sealed := strings.TrimSuffix(name, spoolActiveExt) + spoolSealedExt
+			if err := os.Rename(filepath.Join(dir, name), filepath.Join(dir, sealed)); err != nil {
+				return nil, fmt.Errorf("bugfixes: seal spool segment: %w", err)
+			}
+		}
+	}
+	if err := syncDir(dir); err != nil {
+		return nil, err
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, segment := range segments {
+		s.size += segment.size
+	}
+
+	return s, nil
+}
+
+// Append writes an event to the active segment and fsyncs it before
+// returning, then trims the oldest segments if the spool is over its size cap.
+func (s *Spool) Append(event Event) error {
+	return s.append(spoolRecord{Event: event})
+}
+
+func (s *Spool) append(record spoolRecord) error {
+	if record.Created.IsZero() {
+		record.Created = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("bugfixes: encode spool record: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		s.seq++
+		s.activeName = fmt.Sprintf("%020d-%06d", time.Now().UnixNano(), s.seq)
+		s.active, err = os.OpenFile(filepath.Join(s.dir, s.activeName+spoolActiveExt), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			s.active = nil
+			return fmt.Errorf("bugfixes: open spool segment: %w", err)
+		}
+	}
+
+	n, err := s.active.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("bugfixes: write spool segment: %w", err)
+	}
+	if err := s.active.Sync(); err != nil {
+		return fmt.Errorf("bugfixes: sync spool segment: %w", err)
+	}
+
+	if info, err := s.active.Stat(); err == nil && info.Size() >= spoolSegmentMaxBytes {
+		if err := s.sealLocked(); err != nil {
+			return err
+		}
+	}
+
+	return s.trimLocked()
+}
+
+// Replay resends every spooled record, oldest first. Records older than the
+// age cap or rejected permanently are discarded. Replay stops at the first
+// transient failure, keeping that record and everything after it on disk.
+func (s *Spool) Replay(ctx context.Context, send SendFunc) error {
+	return s.replay(ctx, sendEvent(send))
+}
+
+// sendEvent adapts send to spooled records, ignoring their owner.
+func sendEvent(send SendFunc) recordSendFunc {
+	return func(ctx context.Context, record spoolRecord) error {
+		return send(ctx, record.Event)
+	}
+}
+
+func (s *Spool) replay(ctx context.Context, send recordSendFunc) error {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+
+	s.mu.Lock()
+	err := s.sealLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range segments {
+		if err := s.replaySegment(ctx, segment, send); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Spool) replaySegment(ctx context.Context, segment spoolSegment, send recordSendFunc) error {
+	s.mu.Lock()
+	s.replayingName = segment.name
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.replayingName = ""
+		s.mu.Unlock()
+	}()
+
+	records, err := readSegment(segment.path)
+	if err != nil {
+		return err
+	}
+
+	var kept []spoolRecord
+	cutoff := time.Now().Add(-s.maxAge)
+	for i, record := range records {
+		if record.Created.Before(cutoff) {
+			s.drop(DropSpoolExpired, []spoolRecord{record})
+			continue
+		}
+
+		sendErr := send(ctx, record)
+		if sendErr == nil || permanent(sendErr) {
+			continue
+		}
+		if errors.Is(sendErr, errNoSpoolOwner) {
+			kept = append(kept, record)
+			continue
+		}
+
+		if err := s.rewriteSegment(segment, append(kept, records[i:]...)); err != nil {
+			return err
+		}
+		return sendErr
+	}
+
+	if len(kept) > 0 {
+		return s.rewriteSegment(segment, kept)
+	}
+
+	return s.removeSegment(segment)
+}
+
+// Start runs a background replayer that calls Replay every interval, and
+// whenever Kick is called.
+func (s *Spool) Start(interval time.Duration, send SendFunc) {
+	s.start(interval, sendEvent(send))
+}
+
+func (s *Spool) start(interval time.Duration, send recordSendFunc) {
+	if interval <= 0 {
+		interval = DefaultSpoolReplayInterval
+	}
+
+	s.mu.Lock()
+	if s.stop != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	stop, done := s.stop, s.done
+	s.mu.Unlock()
+
+	go func() {
+		defer close(done)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			<-stop
+			cancel()
+		}()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			case <-s.kick:
+			}
+			_ = s.replay(ctx, send)
+		}
+	}()
+}
+
+// Kick asks the background replayer to run a pass now, for example after a
+// live delivery has succeeded.
+func (s *Spool) Kick() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Size returns the number of bytes currently spooled.
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// Close stops the replayer and seals the active segment.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop = nil
+	s.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sealLocked()
+}
+
+func (s *Spool) sealLocked() error {
+	if s.active == nil {
+		return nil
+	}
+
+	active := s.active
+	s.active = nil
+	if err := active.Close(); err != nil {
+		return fmt.Errorf("bugfixes: close spool segment: %w", err)
+	}
+
+	from := filepath.Join(s.dir, s.activeName+spoolActiveExt)
+	to := filepath.Join(s.dir, s.activeName+spoolSealedExt)
+	if err := os.Rename(from, to); err != nil {
+		return fmt.Errorf("bugfixes: seal spool segment: %w", err)
+	}
+
+	return syncDir(s.dir)
+}
+
+// trimLocked removes the oldest sealed segments until the spool fits within
+// its size cap, along with any segment past the age cap.
+func (s *Spool) trimLocked() error {
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-s.maxAge)
+	for _, segment := range segments {
+		if segment.name == s.replayingName {
+			continue
+		}
+		if s.size <= s.maxBytes && segment.modified.After(cutoff) {
+			continue
+		}
+		reason := DropSpoolExpired
+		if segment.modified.After(cutoff) {
+			reason = DropSpoolFull
+		}
+		var records []spoolRecord
+		if s.onDrop != nil {
+			records, _ = readSegment(segment.path)
+		}
+		if err := os.Remove(segment.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("bugfixes: trim spool: %w", err)
+		}
+		s.size -= segment.size
+		s.drop(reason, records)
+	}
+
+	return nil
+}
+
+// drop reports records discarded unsent to onDrop, counted per owner.
+func (s *Spool) drop(reason string, records []spoolRecord) {
+	if s.onDrop == nil {
+		return
+	}
+
+	counts := map[string]uint64{}
+	for _, record := range records {
+		counts[record.Owner]++
+	}
+	for owner, n := range counts {
+		s.onDrop(reason, owner, n)
+	}
+}
+
+// rewriteSegment atomically replaces a sealed segment with the given records.
+func (s *Spool) rewriteSegment(segment spoolSegment, records []spoolRecord) error {
+	tmp := filepath.Join(s.dir, segment.name+spoolTempExt)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("bugfixes: rewrite spool segment: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("bugfixes: rewrite spool segment: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("bugfixes: rewrite spool segment: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("bugfixes: sync spool segment: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("bugfixes: rewrite spool segment: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("bugfixes: rewrite spool segment: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Rename(tmp, segment.path); err != nil {
+		return fmt.Errorf("bugfixes: rewrite spool segment: %w", err)
+	}
+	s.size += info.Size() - segment.size
+
+	return syncDir(s.dir)
+}
+
+func (s *Spool) removeSegment(segment spoolSegment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(segment.path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("bugfixes: remove spool segment: %w", err)
+	}
+	s.size -= segment.size
+
+	return nil
+}
+
+type spoolSegment struct {
+	name     string
+	path     string
+	size     int64
+	modified time.Time
+}
+
+// segments lists sealed segments, oldest first.
+func (s *Spool) segments() ([]spoolSegment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("bugfixes: read spool: %w", err)
+	}
+
+	var segments []spoolSegment
+	for _, entry := range entries {
+		name := entry.Name()
+		if filepath.Ext(name) != spoolSealedExt {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		segments = append(segments, spoolSegment{
+			name:     strings.TrimSuffix(name, spoolSealedExt),
+			path:     filepath.Join(s.dir, name),
+			size:     info.Size(),
+			modified: info.ModTime(),
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].name < segments[j].name
+	})
+
+	return segments, nil
+}
+
+// readSegment decodes every complete record in a segment. A torn final line,
+// left by a crash mid-write, is skipped.
+func readSegment(path string) ([]spoolRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("bugfixes: open spool segment: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var records []spoolRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), spoolSegmentMaxBytes*4)
+	for scanner.Scan() {
+		var record spoolRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return records, fmt.Errorf("bugfixes: read spool segment: %w", err)
+	}
+
+	return records, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("bugfixes: open spool dir: %w", err)
+	}
+	defer func() {
+		_ = d.Close()
+	}()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("bugfixes: sync spool dir: %w", err)
+	}
+
+	return nil
+}
+
+// sharedSpool is a spool opened for a SpoolDir, with the config of each
+// client and destination writing to it, keyed by spoolOwner. Every record is
+// replayed with its own owner's config.
+type sharedSpool struct {
+	*Spool
+
+	ownersMu sync.Mutex
+	owners   map[string]Config
+	// opener is the owner that opened the spool. Records without an owner,
+	// written by Append or by older versions, are replayed with its config.
+	opener string
+}
+
+var (
+	spoolsMu sync.Mutex
+	spools   = map[string]*sharedSpool{}
+)
+
+// spoolOwner identifies the destination and credentials cfg reports with.
+// The agent key is hashed so it is not written to disk.
+func spoolOwner(cfg Config) string {
+	sum := sha256.Sum256([]byte(cfg.destination + "\n" + cfg.Server + "\n" + cfg.AgentKey))
+	return hex.EncodeToString(sum[:16])
+}
+
+// spoolFor returns the spool configured on cfg, opening it and starting its
+// replayer on first use, and registers cfg as the owner of the records it
+// spools. It returns nil if cfg has no SpoolDir.
+func spoolFor(cfg Config) (*sharedSpool, error) {
+	if cfg.SpoolDir == "" {
+		return nil, nil
+	}
+
+	spoolsMu.Lock()
+	defer spoolsMu.Unlock()
+
+	owner := spoolOwner(cfg)
+	if s, ok := spools[cfg.SpoolDir]; ok {
+		s.ownersMu.Lock()
+		s.owners[owner] = cfg
+		s.ownersMu.Unlock()
+		return s, nil
+	}
+
+	opened, err := OpenSpool(cfg.SpoolDir, cfg.SpoolMaxBytes, cfg.SpoolMaxAge)
+	if err != nil {
+		return nil, err
+	}
+	s := &sharedSpool{
+		Spool:  opened,
+		owners: map[string]Config{owner: cfg},
+		opener: owner,
+	}
+	opened.onDrop = s.drop
+	s.start(cfg.SpoolReplayInterval, s.send)
+	spools[cfg.SpoolDir] = s
+
+	return s, nil
+}
+
+// append spools event for cfg.
+func (s *sharedSpool) append(cfg Config, event Event) error {
+	return s.Spool.append(spoolRecord{Event: event, Owner: spoolOwner(cfg)})
+}
+
+// drop counts records discarded unsent in the stats of the client that
+// spooled them, or only in the process-wide stats if it is not reporting.
+func (s *sharedSpool) drop(reason, owner string, n uint64) {
+	if owner == "" {
+		owner = s.opener
+	}
+
+	s.ownersMu.Lock()
+	cfg, ok := s.owners[owner]
+	s.ownersMu.Unlock()
+	if !ok {
+		stats.drop(reason, n)
+		return
+	}
+
+	statsFor(cfg).drop(reason, n)
+}
+
+// send replays record with its owner's config.
+func (s *sharedSpool) send(ctx context.Context, record spoolRecord) error {
+	owner := record.Owner
+	if owner == "" {
+		owner = s.opener
+	}
+
+	s.ownersMu.Lock()
+	cfg, ok := s.owners[owner]
+	s.ownersMu.Unlock()
+	if !ok {
+		return errNoSpoolOwner
+	}
+
+	return Send(ctx, cfg, record.Event)
+}
+
+// openedSpool returns the spool configured on cfg if it is already open.
+func openedSpool(cfg Config) *sharedSpool {
+	if cfg.SpoolDir == "" {
+		return nil
+	}
+
+	spoolsMu.Lock()
+	defer spoolsMu.Unlock()
+
+	return spools[cfg.SpoolDir]
+}
+
+// releaseSpools stops replaying the records of every owner c registered,
+// and seals each spool left with no owner. Records already spooled stay on
+// disk until their owner reports again.
+func releaseSpools(c *Client) error {
+	spoolsMu.Lock()
+	var released []*sharedSpool
+	for dir, s := range spools {
+		s.ownersMu.Lock()
+		for owner, cfg := range s.owners {
+			if cfg.owner() == c {
+				delete(s.owners, owner)
+			}
+		}
+		empty := len(s.owners) == 0
+		s.ownersMu.Unlock()
+		if empty {
+			delete(spools, dir)
+			released = append(released, s)
+		}
+	}
+	spoolsMu.Unlock()
+
+	var errs []error
+	for _, s := range released {
+		errs = append(errs, s.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// permanent reports whether err is a rejection that will never succeed, so
+// the report should not be spooled or replayed.
+func permanent(err error) bool {
+	if localFailure(err) {
+		return true
+	}
+
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && !statusErr.Retryable()
+}
@@ -0,0 +1,82 @@
+package bugfixes
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// closeSpools seals every open spool, whichever clients registered it.
+func closeSpools() error {
+	spoolsMu.Lock()
+	open := spools
+	spools = map[string]*sharedSpool{}
+	spoolsMu.Unlock()
+
+	var errs []error
+	for _, s := range open {
+		errs = append(errs, s.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+func TestReleaseSpoolsKeepsOtherClientsOwners(t *testing.T) {
+	t.Cleanup(func() { _ = closeSpools() })
+
+	dir := t.TempDir()
+	tenant := NewClient(Config{AgentKey: "tenant", SpoolDir: dir})
+	if _, err := spoolFor(tenant.Config()); err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	if _, err := spoolFor(Config{AgentKey: "default", SpoolDir: dir}); err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+
+	if err := releaseSpools(defaultClient); err != nil {
+		t.Fatalf("release default spools: %v", err)
+	}
+	s := openedSpool(tenant.Config())
+	if s == nil {
+		t.Fatal("expected the spool to stay open for the other client")
+	}
+	if _, ok := s.owners[spoolOwner(tenant.Config())]; !ok || len(s.owners) != 1 {
+		t.Fatalf("expected only the other client's owner to be left, got %d owners", len(s.owners))
+	}
+
+	if err := releaseSpools(tenant); err != nil {
+		t.Fatalf("release tenant spools: %v", err)
+	}
+	if openedSpool(tenant.Config()) != nil {
+		t.Fatal("expected the spool to be sealed once no owner is left")
+	}
+}
+
+func TestSpoolReportsTrimmedRecords(t *testing.T) {
+	spool, err := OpenSpool(t.TempDir(), 200, 0)
+	if err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	t.Cleanup(func() { _ = spool.Close() })
+
+	dropped := map[string]uint64{}
+	spool.onDrop = func(reason, owner string, n uint64) {
+		if owner != "tenant" {
+			t.Errorf("expected the record's owner, got %q", owner)
+		}
+		dropped[reason] += n
+	}
+
+	offline := func(context.Context, spoolRecord) error { return errors.New("offline") }
+	for range 10 {
+		if err := spool.append(spoolRecord{Event: NewEvent(KindLog, []byte(`{}`)), Owner: "tenant"}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+		// seal each record into its own segment so the oldest can be trimmed
+		_ = spool.replay(context.Background(), offline)
+	}
+
+	if dropped[DropSpoolFull] == 0 {
+		t.Fatalf("expected records trimmed for the size cap to be reported, got %v", dropped)
+	}
+}
@@ -0,0 +1,454 @@
+package bugfixes_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+type sentRecord struct {
+	kind    string
+	payload string
+}
+
+func recordingSend(sent *[]sentRecord, failAfter int) bugfixes.SendFunc {
+	return func(_ context.Context, event bugfixes.Event) error {
+		if failAfter >= 0 && len(*sent) >= failAfter {
+			return errors.New("connection refused")
+		}
+		*sent = append(*sent, sentRecord{kind: event.Kind, payload: string(event.Payload)})
+		return nil
+	}
+}
+
+func TestSpoolReplaysInOrder(t *testing.T) {
+	spool, err := bugfixes.OpenSpool(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	t.Cleanup(func() { _ = spool.Close() })
+
+	for _, payload := range []string{`{"n":1}`, `{"n":2}`, `{"n":3}`} {
+		if err := spool.Append(bugfixes.NewEvent(bugfixes.KindLog, []byte(payload))); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	var sent []sentRecord
+	if err := spool.Replay(context.Background(), recordingSend(&sent, -1)); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	if len(sent) != 3 || sent[0].payload != `{"n":1}` || sent[2].payload != `{"n":3}` {
+		t.Fatalf("expected all records in order, got %+v", sent)
+	}
+	if sent[0].kind != bugfixes.KindLog {
+		t.Fatalf("expected kind to round-trip, got %q", sent[0].kind)
+	}
+	if spool.Size() != 0 {
+		t.Fatalf("expected an empty spool after replay, got %d bytes", spool.Size())
+	}
+}
+
+func TestSpoolKeepsRecordsAfterTransientFailure(t *testing.T) {
+	spool, err := bugfixes.OpenSpool(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	t.Cleanup(func() { _ = spool.Close() })
+
+	for _, payload := range []string{`{"n":1}`, `{"n":2}`, `{"n":3}`} {
+		_ = spool.Append(bugfixes.NewEvent(bugfixes.KindBug, []byte(payload)))
+	}
+
+	var sent []sentRecord
+	if err := spool.Replay(context.Background(), recordingSend(&sent, 1)); err == nil {
+		t.Fatal("expected the transient failure to be returned")
+	}
+	if len(sent) != 1 {
+		t.Fatalf("expected one record delivered, got %d", len(sent))
+	}
+
+	sent = nil
+	if err := spool.Replay(context.Background(), recordingSend(&sent, -1)); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(sent) != 2 || sent[0].payload != `{"n":2}` || sent[1].payload != `{"n":3}` {
+		t.Fatalf("expected the remaining records, got %+v", sent)
+	}
+}
+
+func TestSpoolDropsPermanentlyRejectedRecords(t *testing.T) {
+	spool, err := bugfixes.OpenSpool(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	t.Cleanup(func() { _ = spool.Close() })
+
+	_ = spool.Append(bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`)))
+
+	rejected := func(context.Context, bugfixes.Event) error {
+		return &bugfixes.StatusError{StatusCode: http.StatusBadRequest}
+	}
+	if err := spool.Replay(context.Background(), rejected); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if spool.Size() != 0 {
+		t.Fatalf("expected rejected record to be discarded, got %d bytes", spool.Size())
+	}
+}
+
+func TestSpoolRecoversSegmentsAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+
+	segment := `{"kind":"log","created":"` + time.Now().UTC().Format(time.RFC3339Nano) + `","payload":{"n":1}}` + "\n" +
+		`{"kind":"log","created":"2026-` // torn write
+	if err := os.WriteFile(filepath.Join(dir, "00000000000000000001-000001.open"), []byte(segment), 0o600); err != nil {
+		t.Fatalf("write segment: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "00000000000000000001-000001.tmp"), []byte("junk"), 0o600); err != nil {
+		t.Fatalf("write temp: %v", err)
+	}
+
+	spool, err := bugfixes.OpenSpool(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	t.Cleanup(func() { _ = spool.Close() })
+
+	if _, err := os.Stat(filepath.Join(dir, "00000000000000000001-000001.tmp")); !os.IsNotExist(err) {
+		t.Fatal("expected leftover temp file to be removed")
+	}
+
+	var sent []sentRecord
+	if err := spool.Replay(context.Background(), recordingSend(&sent, -1)); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(sent) != 1 || sent[0].payload != `{"n":1}` {
+		t.Fatalf("expected the complete record to be replayed, got %+v", sent)
+	}
+}
+
+func TestSpoolEnforcesSizeCap(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := bugfixes.OpenSpool(dir, 200, 0)
+	if err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	t.Cleanup(func() { _ = spool.Close() })
+
+	for i := range 10 {
+		if err := spool.Append(bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":`+string(rune('0'+i))+`}`))); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+		// seal each record into its own segment so the oldest can be trimmed
+		if err := spool.Replay(context.Background(), func(context.Context, bugfixes.Event) error {
+			return errors.New("offline")
+		}); err == nil {
+			t.Fatal("expected replay to fail while offline")
+		}
+	}
+
+	if spool.Size() > 200 {
+		t.Fatalf("expected spool to stay under its cap, got %d bytes", spool.Size())
+	}
+
+	var sent []sentRecord
+	if err := spool.Replay(context.Background(), recordingSend(&sent, -1)); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(sent) == 0 || sent[len(sent)-1].payload != `{"n":9}` {
+		t.Fatalf("expected the newest records to survive, got %+v", sent)
+	}
+}
+
+func TestSpoolDropsRecordsPastMaxAge(t *testing.T) {
+	dir := t.TempDir()
+
+	segment := `{"kind":"log","created":"2000-01-01T00:00:00Z","payload":{"n":1}}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "00000000000000000001-000001.jsonl"), []byte(segment), 0o600); err != nil {
+		t.Fatalf("write segment: %v", err)
+	}
+
+	spool, err := bugfixes.OpenSpool(dir, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	t.Cleanup(func() { _ = spool.Close() })
+
+	var sent []sentRecord
+	if err := spool.Replay(context.Background(), recordingSend(&sent, -1)); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("expected expired record to be dropped, got %+v", sent)
+	}
+}
+
+func TestDeliverSpoolsAndReplaysWhenEndpointRecovers(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	var healthy atomic.Bool
+	var delivered atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		delivered.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	cfg := bugfixes.Config{
+		Server:              server.URL,
+		AgentKey:            "key",
+		AgentSecret:         "secret",
+		RetryDeadline:       -1,
+		SpoolDir:            dir,
+		SpoolReplayInterval: time.Hour,
+	}
+
+	if err := bugfixes.Deliver(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":1}`))); err != nil {
+		t.Fatalf("expected the report to be spooled, got %v", err)
+	}
+	if delivered.Load() != 0 {
+		t.Fatal("expected nothing delivered while the endpoint is down")
+	}
+
+	healthy.Store(true)
+	if err := bugfixes.Deliver(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":2}`))); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for delivered.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the spooled report to be replayed, delivered %d", delivered.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDeliverSucceedsWithUnopenableSpool(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	server, _ := statusSequence(t, http.StatusOK)
+	blocker := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(blocker, nil, 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	cfg := retryConfig(server.URL)
+	cfg.SpoolDir = filepath.Join(blocker, "spool")
+
+	if err := bugfixes.Deliver(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))); err != nil {
+		t.Fatalf("expected a delivered report to ignore the spool, got %v", err)
+	}
+}
+
+// keyServer fails until healthy is set, then records each payload's n with
+// the agent key it was sent with.
+func keyServer(t *testing.T, healthy *atomic.Bool) (*httptest.Server, func() map[string]bool) {
+	t.Helper()
+
+	var mu sync.Mutex
+	sent := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var body struct {
+			N string `json:"n"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		sent[body.N+" "+r.Header.Get(bugfixes.HeaderAPIKey)] = true
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() map[string]bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return maps.Clone(sent)
+	}
+}
+
+func waitForKeys(t *testing.T, sent func() map[string]bool, want ...string) {
+	t.Helper()
+
+	expected := map[string]bool{}
+	for _, w := range want {
+		expected[w] = true
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !maps.Equal(sent(), expected) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected reports sent with %v, got %v", want, sent())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSpoolReplaysEachClientWithItsOwnCredentials(t *testing.T) {
+	var healthy atomic.Bool
+	server, sent := keyServer(t, &healthy)
+
+	dir := t.TempDir()
+	base := bugfixes.Config{
+		Server:              server.URL,
+		AgentSecret:         "secret",
+		RetryDeadline:       -1,
+		SpoolDir:            dir,
+		SpoolReplayInterval: time.Hour,
+	}
+	tenantA, tenantB := base, base
+	tenantA.AgentKey = "tenant-a"
+	tenantB.AgentKey = "tenant-b"
+	a := bugfixes.NewClient(tenantA)
+	b := bugfixes.NewClient(tenantB)
+	t.Cleanup(func() {
+		_ = a.Close(context.Background())
+		_ = b.Close(context.Background())
+	})
+
+	ctx := context.Background()
+	if err := bugfixes.Deliver(ctx, a.Config(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":"a1"}`))); err != nil {
+		t.Fatalf("expected tenant a's report to be spooled, got %v", err)
+	}
+	if err := bugfixes.Deliver(ctx, b.Config(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":"b1"}`))); err != nil {
+		t.Fatalf("expected tenant b's report to be spooled, got %v", err)
+	}
+
+	healthy.Store(true)
+	if err := bugfixes.Deliver(ctx, a.Config(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":"a2"}`))); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+
+	waitForKeys(t, sent, "a1 tenant-a", "a2 tenant-a", "b1 tenant-b")
+}
+
+func TestSpoolReplaysEachDestinationWithItsOwnCredentials(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	var healthy atomic.Bool
+	server, sent := keyServer(t, &healthy)
+
+	dir := t.TempDir()
+	cfg := bugfixes.Config{
+		RetryDeadline:       -1,
+		SpoolReplayInterval: time.Hour,
+		Destinations: []bugfixes.Destination{
+			{Name: "primary", Server: server.URL, AgentKey: "primary-key", AgentSecret: "secret", SpoolDir: dir},
+			{Name: "mirror", Server: server.URL, AgentKey: "mirror-key", AgentSecret: "secret", SpoolDir: dir},
+		},
+	}
+
+	ctx := context.Background()
+	if err := bugfixes.Submit(cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":"1"}`))); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if err := bugfixes.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	healthy.Store(true)
+	primary := cfg
+	primary.Destinations = cfg.Destinations[:1]
+	if err := bugfixes.Submit(primary, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":"2"}`))); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if err := bugfixes.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	waitForKeys(t, sent, "1 primary-key", "1 mirror-key", "2 primary-key")
+}
+
+func TestCloseKeepsReplayingOtherClientsSpools(t *testing.T) {
+	var healthy atomic.Bool
+	server, sent := keyServer(t, &healthy)
+
+	dir := t.TempDir()
+	cfg := bugfixes.Config{
+		Server:              server.URL,
+		AgentKey:            "default-key",
+		AgentSecret:         "secret",
+		RetryDeadline:       -1,
+		SpoolDir:            dir,
+		SpoolReplayInterval: time.Hour,
+	}
+	tenantCfg := cfg
+	tenantCfg.AgentKey = "tenant-key"
+	tenant := bugfixes.NewClient(tenantCfg)
+	t.Cleanup(func() { _ = tenant.Close(context.Background()) })
+
+	ctx := context.Background()
+	if err := bugfixes.Deliver(ctx, tenant.Config(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":"t1"}`))); err != nil {
+		t.Fatalf("expected the tenant's report to be spooled, got %v", err)
+	}
+	if err := bugfixes.Deliver(ctx, cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":"d1"}`))); err != nil {
+		t.Fatalf("expected the default report to be spooled, got %v", err)
+	}
+	if err := bugfixes.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	healthy.Store(true)
+	if err := bugfixes.Deliver(ctx, tenant.Config(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":"t2"}`))); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+
+	waitForKeys(t, sent, "t1 tenant-key", "t2 tenant-key")
+}
+
+func TestSpoolCountsExpiredRecords(t *testing.T) {
+	var healthy atomic.Bool
+	server, sent := keyServer(t, &healthy)
+
+	client := bugfixes.NewClient(bugfixes.Config{
+		Server:              server.URL,
+		AgentKey:            "expiring-key",
+		AgentSecret:         "secret",
+		RetryDeadline:       -1,
+		SpoolDir:            t.TempDir(),
+		SpoolMaxAge:         20 * time.Millisecond,
+		SpoolReplayInterval: time.Hour,
+	})
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	ctx := context.Background()
+	if err := bugfixes.Deliver(ctx, client.Config(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":"old"}`))); err != nil {
+		t.Fatalf("expected the report to be spooled, got %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	healthy.Store(true)
+	if err := bugfixes.Deliver(ctx, client.Config(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":"new"}`))); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Stats().Dropped[bugfixes.DropSpoolExpired] != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the expired record to be counted, got %v", client.Stats().Dropped)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	waitForKeys(t, sent, "new expiring-key")
+}
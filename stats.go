@@ -0,0 +1,183 @@
+package bugfixes
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Reasons a report is dropped without being sent.
+const (
+	DropQueueFull   = "queue_full"
+	DropQueueClosed = "queue_closed"
+	DropSampled     = "sampled"
+	DropRateLimited = "rate_limited"
+	DropDuplicate   = "duplicate"
+	// DropSpoolFull and DropSpoolExpired count spooled reports discarded
+	// for SpoolMaxBytes and SpoolMaxAge.
+	DropSpoolFull    = "spool_full"
+	DropSpoolExpired = "spool_expired"
+)
+
+// DeliveryStats is a snapshot of the reporting pipeline, of one client or of
+// the whole process, since the process started.
+type DeliveryStats struct {
+	// Queued is the number of reports waiting in the delivery queues.
+	Queued int `json:"queued"`
+	// Sent counts reports accepted by a transport, including replays.
+	Sent uint64 `json:"sent"`
+	// Retried counts extra attempts made after a transient failure.
+	Retried uint64 `json:"retried"`
+	// Spooled counts reports written to the spool for later replay.
+	Spooled uint64 `json:"spooled"`
+	// Dropped counts reports discarded before sending, by reason.
+	Dropped map[string]uint64 `json:"dropped"`
+	// Failed counts reports that could not be sent, by HTTP status code.
+	// Failures without a response, such as network errors or an open
+	// circuit breaker, are counted under 0.
+	Failed map[int]uint64 `json:"failed"`
+
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at,omitzero"`
+	LastSuccess time.Time `json:"last_success,omitzero"`
+}
+
+type statsRecorder struct {
+	mu    sync.Mutex
+	stats DeliveryStats
+}
+
+// stats sums the statistics of every client.
+var stats = &statsRecorder{}
+
+// statsRecorders are what an event sent with a config is counted in: the
+// process-wide statistics and those of the config's client.
+type statsRecorders [2]*statsRecorder
+
+func statsFor(cfg Config) statsRecorders {
+	return statsRecorders{stats, &cfg.owner().stats}
+}
+
+func init() {
+	expvar.Publish("bugfixes", expvar.Func(func() any { return Stats() }))
+}
+
+// Stats returns a snapshot of the delivery statistics of the whole process,
+// summed over every client, with Queued counting the reports waiting in
+// DefaultClient's queues. Use Client.Stats to tell clients apart.
+func Stats() DeliveryStats {
+	snapshot := stats.snapshot()
+	snapshot.Queued = defaultClient.Queued()
+
+	return snapshot
+}
+
+// Stats returns a snapshot of the delivery statistics of the reports made
+// through c, with Queued counting the reports waiting in c's queues.
+func (c *Client) Stats() DeliveryStats {
+	snapshot := c.stats.snapshot()
+	snapshot.Queued = c.Queued()
+
+	return snapshot
+}
+
+// StatsHandler serves Stats as JSON.
+func StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Stats())
+	})
+}
+
+func (s *statsRecorder) snapshot() DeliveryStats {
+	s.mu.Lock()
+	snapshot := s.stats
+	snapshot.Dropped = maps.Clone(s.stats.Dropped)
+	snapshot.Failed = maps.Clone(s.stats.Failed)
+	s.mu.Unlock()
+
+	if snapshot.Dropped == nil {
+		snapshot.Dropped = map[string]uint64{}
+	}
+	if snapshot.Failed == nil {
+		snapshot.Failed = map[int]uint64{}
+	}
+
+	return snapshot
+}
+
+func (r statsRecorders) drop(reason string, n uint64) {
+	for _, s := range r {
+		s.drop(reason, n)
+	}
+}
+
+func (r statsRecorders) retry() {
+	for _, s := range r {
+		s.retry()
+	}
+}
+
+func (r statsRecorders) spool() {
+	for _, s := range r {
+		s.spool()
+	}
+}
+
+func (r statsRecorders) result(err error) {
+	for _, s := range r {
+		s.result(err)
+	}
+}
+
+func (s *statsRecorder) drop(reason string, n uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stats.Dropped == nil {
+		s.stats.Dropped = make(map[string]uint64)
+	}
+	s.stats.Dropped[reason] += n
+}
+
+func (s *statsRecorder) retry() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Retried++
+}
+
+func (s *statsRecorder) spool() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Spooled++
+}
+
+// result records the final outcome of sending one report.
+func (s *statsRecorder) result(err error) {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		s.stats.Sent++
+		s.stats.LastSuccess = now
+		return
+	}
+
+	code := 0
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		code = statusErr.StatusCode
+	}
+	if s.stats.Failed == nil {
+		s.stats.Failed = make(map[int]uint64)
+	}
+	s.stats.Failed[code]++
+	s.stats.LastError = err.Error()
+	s.stats.LastErrorAt = now
+}
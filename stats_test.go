@@ -0,0 +1,133 @@
+package bugfixes_test
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func TestStatsCountsDeliveryOutcomes(t *testing.T) {
+	before := bugfixes.Stats()
+
+	attempts := 0
+	flaky := bugfixes.TransportFunc(func(context.Context, bugfixes.Event) error {
+		attempts++
+		if attempts == 1 {
+			return &bugfixes.StatusError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+	cfg := bugfixes.Config{
+		Server:              breakerServer(t),
+		Transport:           flaky,
+		RetryInitialBackoff: time.Millisecond,
+		RetryMaxBackoff:     time.Millisecond,
+	}
+	if err := bugfixes.Send(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	cfg.RetryDeadline = -1
+	cfg.Transport = bugfixes.TransportFunc(func(context.Context, bugfixes.Event) error {
+		return &bugfixes.StatusError{StatusCode: http.StatusBadRequest}
+	})
+	if err := bugfixes.Send(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))); err == nil {
+		t.Fatal("expected the rejection to be returned")
+	}
+
+	after := bugfixes.Stats()
+	if after.Sent-before.Sent != 1 {
+		t.Fatalf("expected one report sent, got %d", after.Sent-before.Sent)
+	}
+	if after.Retried-before.Retried != 1 {
+		t.Fatalf("expected one retry, got %d", after.Retried-before.Retried)
+	}
+	if after.Failed[http.StatusBadRequest]-before.Failed[http.StatusBadRequest] != 1 {
+		t.Fatalf("expected one failure under 400, got %v", after.Failed)
+	}
+	if !strings.Contains(after.LastError, "400") {
+		t.Fatalf("expected the last error to be recorded, got %q", after.LastError)
+	}
+	if after.LastSuccess.IsZero() {
+		t.Fatal("expected the last success time to be recorded")
+	}
+}
+
+func TestStatsCountsDropsByReason(t *testing.T) {
+	t.Cleanup(func() { _ = bugfixes.Close(context.Background()) })
+
+	before := bugfixes.Stats()
+	level := uniqueKey(t)
+	cfg := bugfixes.Config{
+		Transport:   bugfixes.NewRecorder(),
+		SampleRates: map[string]float64{level: 0},
+	}
+	submitN(t, cfg, level, "noisy", 3)
+
+	after := bugfixes.Stats()
+	if got := after.Dropped[bugfixes.DropSampled] - before.Dropped[bugfixes.DropSampled]; got != 3 {
+		t.Fatalf("expected 3 sampled out, got %d", got)
+	}
+}
+
+func TestClientStatsAreKeptPerClient(t *testing.T) {
+	failing := bugfixes.NewClient(bugfixes.Config{
+		Server:        breakerServer(t),
+		RetryDeadline: -1,
+		Transport: bugfixes.TransportFunc(func(context.Context, bugfixes.Event) error {
+			return &bugfixes.StatusError{StatusCode: http.StatusBadGateway}
+		}),
+	})
+	healthy := bugfixes.NewClient(bugfixes.Config{Transport: bugfixes.NewRecorder()})
+	t.Cleanup(func() {
+		_ = failing.Close(context.Background())
+		_ = healthy.Close(context.Background())
+	})
+
+	before := bugfixes.Stats()
+	_ = bugfixes.Send(context.Background(), failing.Config(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`)))
+	if err := bugfixes.Send(context.Background(), healthy.Config(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if got := failing.Stats(); got.Sent != 0 || got.Failed[http.StatusBadGateway] != 1 {
+		t.Fatalf("expected only the failure in the failing client's stats, got sent=%d failed=%v", got.Sent, got.Failed)
+	}
+	if got := healthy.Stats(); got.Sent != 1 || len(got.Failed) != 0 {
+		t.Fatalf("expected only the success in the healthy client's stats, got sent=%d failed=%v", got.Sent, got.Failed)
+	}
+
+	after := bugfixes.Stats()
+	if after.Sent-before.Sent != 1 || after.Failed[http.StatusBadGateway]-before.Failed[http.StatusBadGateway] != 1 {
+		t.Fatal("expected Stats to sum every client")
+	}
+}
+
+func TestStatsHandlerServesJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	bugfixes.StatsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/bugfixes", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected JSON, got %q", ct)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for _, field := range []string{"queued", "sent", "retried", "dropped", "failed"} {
+		if _, ok := got[field]; !ok {
+			t.Errorf("expected %q in %s", field, rec.Body)
+		}
+	}
+
+	if expvar.Get("bugfixes") == nil {
+		t.Fatal("expected stats to be published through expvar")
+	}
+}
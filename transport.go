@@ -0,0 +1,413 @@
+package bugfixes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of report accepted by the Bugfixes API.
+const (
+	KindLog = "log"
+	KindBug = "bug"
+)
+
+// DefaultCompressThreshold is the smallest body, in bytes, that is gzipped
+// when compression is enabled.
+const DefaultCompressThreshold = 1024
+
+// Event is a single report ready for delivery. Payload holds the JSON body
+// built by the logs or middleware package; Body adds the delivery metadata
+// to it.
+type Event struct {
+	// ID is generated by NewEvent and sent as event_id, so a report can be
+	// found before the server has answered.
+	ID      string          `json:"id,omitempty"`
+	Kind    string          `json:"kind"`
+	Level   string          `json:"level,omitempty"`
+	Created time.Time       `json:"created"`
+	Payload json.RawMessage `json:"payload"`
+
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	Occurrences int       `json:"occurrences,omitempty"`
+	FirstSeen   time.Time `json:"first_seen,omitzero"`
+	LastSeen    time.Time `json:"last_seen,omitzero"`
+
+	// RequestID, TraceID and Tags tie the event to the work that caused it.
+	RequestID string            `json:"request_id,omitempty"`
+	TraceID   string            `json:"trace_id,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+
+	// Context describes the service and host that produced the event. It is
+	// filled from the Config when the event is submitted.
+	Context EventContext `json:"context,omitzero"`
+
+	// SampleRate is the rate the event's level was sampled at, and Dropped
+	// how many events at that level were sampled out or rate limited since
+	// the previous one was sent.
+	SampleRate float64 `json:"sample_rate,omitempty"`
+	Dropped    int     `json:"dropped,omitempty"`
+
+	// Message and Stack feed the fingerprint and are not sent.
+	Message string `json:"-"`
+	Stack   []byte `json:"-"`
+}
+
+// NewEvent wraps an encoded payload of the given kind.
+func NewEvent(kind string, payload []byte) Event {
+	return Event{
+		ID:      newEventID(),
+		Kind:    kind,
+		Created: time.Now().UTC(),
+		Payload: payload,
+	}
+}
+
+// Body returns the payload with the event's delivery metadata merged in as
+// extra top-level fields. Payloads that are not JSON objects are returned
+// unchanged.
+func (e Event) Body() ([]byte, error) {
+	extra := map[string]any{}
+	if e.ID != "" {
+		extra["event_id"] = e.ID
+	}
+	if e.Fingerprint != "" {
+		extra["fingerprint"] = e.Fingerprint
+	}
+	if e.RequestID != "" {
+		extra["request_id"] = e.RequestID
+	}
+	if e.TraceID != "" {
+		extra["trace_id"] = e.TraceID
+	}
+	if len(e.Tags) > 0 {
+		extra["tags"] = e.Tags
+	}
+	if e.Context != (EventContext{}) {
+		extra["context"] = e.Context
+	}
+	if e.Occurrences > 0 {
+		extra["occurrences"] = e.Occurrences
+		extra["first_seen"] = e.FirstSeen
+		extra["last_seen"] = e.LastSeen
+	}
+	if e.SampleRate > 0 {
+		extra["sample_rate"] = e.SampleRate
+	}
+	if e.Dropped > 0 {
+		extra["dropped"] = e.Dropped
+	}
+	if len(extra) == 0 {
+		return e.Payload, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(e.Payload, &fields); err != nil || fields == nil {
+		return e.Payload, nil
+	}
+	for key, value := range extra {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: encode %s: %w", ErrMarshal, key, err)
+		}
+		fields[key] = raw
+	}
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMarshal, err)
+	}
+
+	return body, nil
+}
+
+// newEventID returns a random (version 4) UUID.
+func newEventID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
+
+// Transport delivers events somewhere. Implementations must be safe for
+// concurrent use; errors are classified with Retryable.
+type Transport interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// Result is what is known about a delivered event.
+type Result struct {
+	// ClientID is the ID the event was sent with.
+	ClientID string
+	// EventID is the ID the server assigned, if it returned one.
+	EventID string
+	// Message is any message the server returned.
+	Message string
+}
+
+// ResultTransport is a Transport that can report what the server said about
+// an event.
+type ResultTransport interface {
+	Transport
+	SendResult(ctx context.Context, event Event) (Result, error)
+}
+
+// TransportFunc adapts a function to the Transport interface.
+type TransportFunc func(ctx context.Context, event Event) error
+
+func (f TransportFunc) Send(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// HTTPTransport posts events to the Bugfixes API. It is the default
+// transport.
+type HTTPTransport struct {
+	Server      string
+	AgentKey    string
+	AgentSecret string
+	Client      *http.Client
+
+	// Timeout bounds each attempt, whatever the client's own timeout. Zero
+	// means DefaultTimeout.
+	Timeout time.Duration
+
+	// Sign sends an HMAC signature instead of the agent secret.
+	Sign bool
+
+	// Compress gzips bodies of at least CompressThreshold bytes. If the
+	// server answers 415 the body is resent uncompressed and compression is
+	// not attempted against that server again.
+	Compress          bool
+	CompressThreshold int
+}
+
+// NewHTTPTransport returns an HTTP transport using cfg's server,
+// credentials and client.
+func NewHTTPTransport(cfg Config) *HTTPTransport {
+	cfg = cfg.normalized()
+	return &HTTPTransport{
+		Server:            cfg.Server,
+		AgentKey:          cfg.AgentKey,
+		AgentSecret:       cfg.AgentSecret,
+		Client:            cfg.GetHTTPClient(),
+		Sign:              cfg.SignRequests,
+		Compress:          cfg.Compress,
+		CompressThreshold: cfg.CompressThreshold,
+	}
+}
+
+// gzipRejected holds the servers that have answered a gzipped body with 415.
+var gzipRejected sync.Map
+
+func (t *HTTPTransport) Send(ctx context.Context, event Event) error {
+	_, err := t.SendResult(ctx, event)
+	return err
+}
+
+// SendResult sends event and parses the event ID and message from the
+// server's response.
+func (t *HTTPTransport) SendResult(ctx context.Context, event Event) (Result, error) {
+	endpoint := strings.TrimRight(t.Server, "/") + "/" + event.Kind
+	payload, err := event.Body()
+	if err != nil {
+		return Result{}, err
+	}
+
+	threshold := t.CompressThreshold
+	if threshold <= 0 {
+		threshold = DefaultCompressThreshold
+	}
+	if _, rejected := gzipRejected.Load(t.Server); t.Compress && !rejected && len(payload) >= threshold {
+		body, err := gzipBody(payload)
+		if err != nil {
+			return Result{}, err
+		}
+
+		result, err := t.post(ctx, endpoint, body, true)
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnsupportedMediaType {
+			result.ClientID = event.ID
+			return result, err
+		}
+		gzipRejected.Store(t.Server, true)
+	}
+
+	result, err := t.post(ctx, endpoint, payload, false)
+	result.ClientID = event.ID
+	return result, err
+}
+
+func (t *HTTPTransport) post(ctx context.Context, endpoint string, body []byte, gzipped bool) (Result, error) {
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: build request: %w", ErrInvalidConfig, err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if gzipped {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+	if t.Sign {
+		if err := SignRequest(request, t.AgentKey, t.AgentSecret, body); err != nil {
+			return Result{}, err
+		}
+	} else {
+		request.Header.Set(HeaderAPIKey, t.AgentKey)
+		request.Header.Set(HeaderAPISecret, t.AgentSecret)
+	}
+
+	client := t.Client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return Result{}, err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return parseResult(io.LimitReader(resp.Body, 64<<10)), nil
+	}
+
+	return Result{}, &StatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseResult reads the event ID and message from a response body. Bodies
+// that are not JSON yield an empty result.
+func parseResult(r io.Reader) Result {
+	var response struct {
+		ID      string `json:"id"`
+		EventID string `json:"event_id"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return Result{}
+	}
+
+	result := Result{EventID: response.EventID, Message: response.Message}
+	if result.EventID == "" {
+		result.EventID = response.ID
+	}
+
+	return result
+}
+
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, fmt.Errorf("%w: compress body: %w", ErrMarshal, err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("%w: compress body: %w", ErrMarshal, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// WriterTransport writes each event to an io.Writer as a line of JSON.
+type WriterTransport struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterTransport(w io.Writer) *WriterTransport {
+	return &WriterTransport{w: w}
+}
+
+func (t *WriterTransport) Send(_ context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%w: encode event: %w", ErrMarshal, err)
+	}
+	line = append(line, '\n')
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, err = t.w.Write(line)
+	return err
+}
+
+// FileTransport appends each event to a local file as a line of JSON.
+type FileTransport struct {
+	*WriterTransport
+	f *os.File
+}
+
+// NewFileTransport opens path for appending, creating it if needed.
+func NewFileTransport(path string) (*FileTransport, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("bugfixes: open transport file: %w", err)
+	}
+
+	return &FileTransport{
+		WriterTransport: NewWriterTransport(f),
+		f:               f,
+	}, nil
+}
+
+func (t *FileTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.f.Close()
+}
+
+// Recorder keeps every event in memory. It is intended for tests and local
+// development.
+type Recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Send(_ context.Context, event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+// Events returns a copy of the recorded events, oldest first.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+// Reset discards the recorded events.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
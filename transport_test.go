@@ -0,0 +1,242 @@
+package bugfixes_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func TestHTTPTransportPostsToKindEndpoint(t *testing.T) {
+	var gotPath, gotKey, gotSecret, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath = r.URL.Path
+		gotKey = r.Header.Get("X-API-KEY")
+		gotSecret = r.Header.Get("X-API-SECRET")
+		gotBody = string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+
+	transport := bugfixes.NewHTTPTransport(bugfixes.Config{
+		Server:      server.URL + "/v1/",
+		AgentKey:    "key",
+		AgentSecret: "secret",
+	})
+
+	event := bugfixes.NewEvent(bugfixes.KindBug, []byte(`{"bug":"x"}`))
+	if err := transport.Send(context.Background(), event); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if gotPath != "/v1/bug" {
+		t.Fatalf("expected /v1/bug, got %q", gotPath)
+	}
+	if gotKey != "key" || gotSecret != "secret" {
+		t.Fatalf("expected credentials to be sent, got %q/%q", gotKey, gotSecret)
+	}
+	if gotBody != `{"bug":"x","event_id":"`+event.ID+`"}` {
+		t.Fatalf("expected payload and event ID as the body, got %q", gotBody)
+	}
+}
+
+func TestWriterTransportWritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	transport := bugfixes.NewWriterTransport(&buf)
+
+	_ = transport.Send(context.Background(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":1}`)))
+	_ = transport.Send(context.Background(), bugfixes.NewEvent(bugfixes.KindBug, []byte(`{"n":2}`)))
+
+	var events []bugfixes.Event
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event bugfixes.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("decode line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+
+	if len(events) != 2 || events[0].Kind != bugfixes.KindLog || string(events[1].Payload) != `{"n":2}` {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
+
+func TestFileTransportAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports.jsonl")
+
+	for range 2 {
+		transport, err := bugfixes.NewFileTransport(path)
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		if err := transport.Send(context.Background(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		if err := transport.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != 2 {
+		t.Fatalf("expected 2 lines, got %d", lines)
+	}
+}
+
+func TestSendUsesConfiguredTransport(t *testing.T) {
+	recorder := bugfixes.NewRecorder()
+	cfg := bugfixes.Config{Transport: recorder}
+
+	if err := bugfixes.Send(context.Background(), cfg, bugfixes.NewEvent(bugfixes.KindLog, []byte(`{}`))); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	events := recorder.Events()
+	if len(events) != 1 || events[0].Kind != bugfixes.KindLog {
+		t.Fatalf("expected one recorded log event, got %+v", events)
+	}
+
+	recorder.Reset()
+	if len(recorder.Events()) != 0 {
+		t.Fatal("expected Reset to discard events")
+	}
+}
+
+type gzipCall struct {
+	encoding string
+	body     string
+}
+
+func gzipServer(t *testing.T, rejectGzip bool) (*httptest.Server, *[]gzipCall) {
+	t.Helper()
+
+	var calls []gzipCall
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.Header.Get("Content-Encoding")
+		var reader io.Reader = r.Body
+		if encoding == "gzip" {
+			if rejectGzip {
+				calls = append(calls, gzipCall{encoding: encoding})
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("gzip reader: %v", err)
+				return
+			}
+			reader = zr
+		}
+		body, _ := io.ReadAll(reader)
+		calls = append(calls, gzipCall{encoding: encoding, body: string(body)})
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func TestHTTPTransportCompressesLargeBodies(t *testing.T) {
+	server, calls := gzipServer(t, false)
+	transport := bugfixes.NewHTTPTransport(bugfixes.Config{
+		Server:            server.URL,
+		Compress:          true,
+		CompressThreshold: 64,
+	})
+
+	large := `{"stack":"` + strings.Repeat("goroutine 1 [running]\n", 20) + `"}`
+	_ = transport.Send(context.Background(), bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":1}`)))
+	_ = transport.Send(context.Background(), bugfixes.NewEvent(bugfixes.KindLog, []byte(large)))
+
+	if len(*calls) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(*calls))
+	}
+	if (*calls)[0].encoding != "" {
+		t.Fatal("expected small body to be sent uncompressed")
+	}
+	if (*calls)[1].encoding != "gzip" || (*calls)[1].body != large {
+		t.Fatalf("expected large body gzipped, got %+v", (*calls)[1])
+	}
+}
+
+func TestHTTPTransportFallsBackWhenGzipUnsupported(t *testing.T) {
+	server, calls := gzipServer(t, true)
+	transport := bugfixes.NewHTTPTransport(bugfixes.Config{
+		Server:            server.URL,
+		Compress:          true,
+		CompressThreshold: 1,
+	})
+
+	event := bugfixes.NewEvent(bugfixes.KindLog, []byte(`{"n":1}`))
+	for range 2 {
+		if err := transport.Send(context.Background(), event); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	got := *calls
+	if len(got) != 3 {
+		t.Fatalf("expected gzip, fallback, then plain requests, got %+v", got)
+	}
+	if got[0].encoding != "gzip" || got[1].encoding != "" || got[2].encoding != "" {
+		t.Fatalf("unexpected encodings: %+v", got)
+	}
+	if got[1].body != `{"event_id":"`+event.ID+`","n":1}` {
+		t.Fatalf("expected fallback to send the original body, got %q", got[1].body)
+	}
+}
+
+func TestReportContextReturnsServerEventID(t *testing.T) {
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"evt_123","message":"stored"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := bugfixes.Config{
+		Server:      server.URL,
+		AgentKey:    "key",
+		AgentSecret: "secret",
+	}
+	event := bugfixes.NewEvent(bugfixes.KindBug, []byte(`{"bug":"x"}`))
+
+	result, err := bugfixes.ReportContext(context.Background(), cfg, event)
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if result.EventID != "evt_123" || result.Message != "stored" {
+		t.Fatalf("expected the server's answer, got %+v", result)
+	}
+	if result.ClientID != event.ID || gotBody["event_id"] != event.ID {
+		t.Fatalf("expected client ID %q in the result and body, got %+v and %v", event.ID, result, gotBody["event_id"])
+	}
+}
+
+func TestNewEventGeneratesUUIDs(t *testing.T) {
+	a := bugfixes.NewEvent(bugfixes.KindLog, nil)
+	b := bugfixes.NewEvent(bugfixes.KindLog, nil)
+
+	if len(a.ID) != 36 || a.ID[14] != '4' {
+		t.Fatalf("expected a version 4 UUID, got %q", a.ID)
+	}
+	if a.ID == b.ID {
+		t.Fatalf("expected unique IDs, got %q twice", a.ID)
+	}
+}
@@ -0,0 +1,89 @@
+package bugfixes
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Validate reports every problem with c that would otherwise only surface
+// when a report is sent. Each problem wraps ErrInvalidConfig; they are
+// returned together with errors.Join.
+func (c Config) Validate() error {
+	var errs []error
+
+	errs = append(errs, validateServer("Server", c.Server))
+	errs = append(errs, validateCredentials("", c.AgentKey, c.AgentSecret))
+	errs = append(errs, validateLevel("LogLevel", c.LogLevel))
+
+	if c.HTTPClient != nil && c.HTTPClient.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("%w: HTTPClient timeout %v is negative; use zero for no client timeout", ErrInvalidConfig, c.HTTPClient.Timeout))
+	}
+
+	for level, rate := range c.SampleRates {
+		if rate < 0 || rate > 1 {
+			errs = append(errs, fmt.Errorf("%w: sample rate %v for %q must be between 0 and 1", ErrInvalidConfig, rate, level))
+		}
+	}
+
+	for i, d := range c.Destinations {
+		name := d.Name
+		if name == "" {
+			name = "destination " + strconv.Itoa(i)
+		}
+		if d.Transport == nil {
+			errs = append(errs, validateServer(name+" Server", d.Server))
+			errs = append(errs, validateCredentials(name+" ", d.AgentKey, d.AgentSecret))
+		}
+		errs = append(errs, validateLevel(name+" MinLevel", d.MinLevel))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateServer(field, server string) error {
+	if server == "" {
+		return nil
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return fmt.Errorf("%w: %s %q is not a valid URL: %w", ErrInvalidConfig, field, server, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: %s %q must use http or https", ErrInvalidConfig, field, server)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%w: %s %q has no host", ErrInvalidConfig, field, server)
+	}
+
+	return nil
+}
+
+func validateCredentials(prefix, key, secret string) error {
+	switch {
+	case key != "" && secret == "":
+		return fmt.Errorf("%w: %sAgentKey is set without AgentSecret; set BUGFIXES_AGENT_SECRET", ErrInvalidConfig, prefix)
+	case key == "" && secret != "":
+		return fmt.Errorf("%w: %sAgentSecret is set without AgentKey; set BUGFIXES_AGENT_KEY", ErrInvalidConfig, prefix)
+	}
+
+	return nil
+}
+
+// validateLevel accepts an empty level, a level name, or a number from
+// LevelDebug to LevelUnknown.
+func validateLevel(field, level string) error {
+	switch level {
+	case "", LOG, DEBUG, INFO, WARN, ERROR, CRASH, PANIC, FATAL, UNKNOWN:
+		return nil
+	}
+
+	if n, err := strconv.Atoi(level); err == nil && n >= LevelDebug && n <= LevelUnknown {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s %q is not a level; use one of debug, log, info, warn, error, crash, panic, fatal or a number from %d to %d",
+		ErrInvalidConfig, field, level, LevelDebug, LevelUnknown)
+}
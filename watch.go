@@ -0,0 +1,190 @@
+package bugfixes
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultWatchInterval is how often a Watcher checks its file for changes.
+const DefaultWatchInterval = 2 * time.Second
+
+// WatchOptions configures WatchConfig.
+type WatchOptions struct {
+	// Path is the config file. It defaults to BUGFIXES_CONFIG.
+	Path string
+
+	// Interval is how often the file is checked for modification. Zero
+	// means DefaultWatchInterval; a negative interval reloads on SIGHUP
+	// only.
+	Interval time.Duration
+
+	// Base supplies the defaults under the file, including fields a file
+	// cannot set such as Transport, HTTPClient and OnInternalError.
+	Base Config
+
+	// Overrides are applied over the file and environment on every load.
+	Overrides []Settings
+
+	// Client receives each config loaded. Nil means DefaultClient.
+	Client *Client
+}
+
+// Watcher reloads a config file on SIGHUP or when it changes, and swaps the
+// result in as its client's config. Queue settings are read when the queue
+// first starts and are not reloaded.
+type Watcher struct {
+	opts WatchOptions
+
+	mu       sync.Mutex
+	current  Config
+	stamp    fileStamp
+	onChange []func(Config)
+
+	hangup chan os.Signal
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// WatchConfig loads the config file, installs it on the client and watches
+// it until Close. It fails if the file cannot be loaded or does not
+// pass Validate; later failed reloads keep the current config and are
+// reported through ReportInternalError.
+func WatchConfig(opts WatchOptions) (*Watcher, error) {
+	if opts.Path == "" {
+		opts.Path = os.Getenv("BUGFIXES_CONFIG")
+	}
+	if opts.Path == "" {
+		return nil, fmt.Errorf("%w: no config file to watch; set a path or BUGFIXES_CONFIG", ErrInvalidConfig)
+	}
+	if opts.Interval == 0 {
+		opts.Interval = DefaultWatchInterval
+	}
+	if opts.Client == nil {
+		opts.Client = defaultClient
+	}
+
+	w := &Watcher{
+		opts:   opts,
+		hangup: make(chan os.Signal, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := w.Reload(); err != nil {
+		return nil, err
+	}
+
+	// registered before returning, so a SIGHUP sent straight after is not
+	// left to its default action
+	signal.Notify(w.hangup, syscall.SIGHUP)
+	go w.run()
+
+	return w, nil
+}
+
+// OnChange registers fn to be called with each config swapped in after a
+// reload, e.g. to update middleware settings.
+func (w *Watcher) OnChange(fn func(Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onChange = append(w.onChange, fn)
+}
+
+// Config returns the config most recently swapped in.
+func (w *Watcher) Config() Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Reload re-reads the file and, if it is valid, swaps it in and calls the
+// OnChange callbacks. An invalid file leaves the current config in place.
+func (w *Watcher) Reload() error {
+	stamp, _ := statFile(w.opts.Path)
+
+	cfg, _, err := loadConfig(w.opts.Base, w.opts.Path, w.opts.Overrides)
+	if err == nil {
+		err = cfg.Validate()
+	}
+
+	w.mu.Lock()
+	w.stamp = stamp
+	if err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	w.current = cfg
+	callbacks := append([]func(Config){}, w.onChange...)
+	_ = w.opts.Client.setConfig(cfg, false)
+	w.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(cfg)
+	}
+
+	return nil
+}
+
+// Close stops watching. The current default config is left in place.
+func (w *Watcher) Close() error {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+	<-w.done
+
+	return nil
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+	defer signal.Stop(w.hangup)
+
+	var tick <-chan time.Time
+	if w.opts.Interval > 0 {
+		ticker := time.NewTicker(w.opts.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.hangup:
+			w.reload()
+		case <-tick:
+			stamp, err := statFile(w.opts.Path)
+			w.mu.Lock()
+			changed := err == nil && stamp != w.stamp
+			w.mu.Unlock()
+			if changed {
+				w.reload()
+			}
+		}
+	}
+}
+
+func (w *Watcher) reload() {
+	if err := w.Reload(); err != nil {
+		ReportInternalError(w.Config(), fmt.Errorf("reload %s: %w", w.opts.Path, err))
+	}
+}
+
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
@@ -0,0 +1,120 @@
+package bugfixes_test
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	bugfixes "github.com/bugfixes/go-bugfixes"
+)
+
+func TestWatchConfigReloadsOnChange(t *testing.T) {
+	clearConfigEnv(t)
+	t.Cleanup(bugfixes.ResetDefaultConfig)
+
+	path := writeConfigFile(t, "bugfixes.yaml", "log_level: error\n")
+	reported := make(chan error, 4)
+	watcher, err := bugfixes.WatchConfig(bugfixes.WatchOptions{
+		Path:     path,
+		Interval: 10 * time.Millisecond,
+		Base:     bugfixes.Config{OnInternalError: func(err error) { reported <- err }},
+	})
+	if err != nil {
+		t.Fatalf("watch config: %v", err)
+	}
+	t.Cleanup(func() { _ = watcher.Close() })
+
+	if got := bugfixes.GetDefaultConfig().LogLevel; got != bugfixes.ERROR {
+		t.Fatalf("expected the file to be installed, got %q", got)
+	}
+
+	changed := make(chan bugfixes.Config, 4)
+	watcher.OnChange(func(cfg bugfixes.Config) { changed <- cfg })
+
+	if err := os.WriteFile(path, []byte("log_level: debug\nservice: api\n"), 0o600); err != nil {
+		t.Fatalf("rewrite config: %v", err)
+	}
+	select {
+	case cfg := <-changed:
+		if cfg.LogLevel != bugfixes.DEBUG || cfg.Service != "api" {
+			t.Fatalf("expected the new file, got %q and %q", cfg.LogLevel, cfg.Service)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the change to be picked up")
+	}
+	if got := bugfixes.GetDefaultConfig().LogLevel; got != bugfixes.DEBUG {
+		t.Fatalf("expected the default config to be swapped, got %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte("log_level: noisy\n"), 0o600); err != nil {
+		t.Fatalf("rewrite config: %v", err)
+	}
+	select {
+	case err := <-reported:
+		if !errors.Is(err, bugfixes.ErrInvalidConfig) {
+			t.Fatalf("expected ErrInvalidConfig, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the invalid file to be reported")
+	}
+	if got := bugfixes.GetDefaultConfig().LogLevel; got != bugfixes.DEBUG {
+		t.Fatalf("expected the previous config to be kept, got %q", got)
+	}
+}
+
+func TestWatchConfigRejectsInvalidFile(t *testing.T) {
+	clearConfigEnv(t)
+	t.Cleanup(bugfixes.ResetDefaultConfig)
+
+	path := writeConfigFile(t, "bugfixes.yaml", "server: ftp://bugfixes.example\n")
+	if _, err := bugfixes.WatchConfig(bugfixes.WatchOptions{Path: path}); !errors.Is(err, bugfixes.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+	if _, err := bugfixes.WatchConfig(bugfixes.WatchOptions{}); !errors.Is(err, bugfixes.ErrInvalidConfig) {
+		t.Fatalf("expected a missing path to be ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestWatchConfigReloadsOnSIGHUP(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGHUP cannot be sent on windows")
+	}
+	clearConfigEnv(t)
+	t.Cleanup(bugfixes.ResetDefaultConfig)
+
+	path := writeConfigFile(t, "bugfixes.yaml", "log_level: error\n")
+	watcher, err := bugfixes.WatchConfig(bugfixes.WatchOptions{Path: path, Interval: -1})
+	if err != nil {
+		t.Fatalf("watch config: %v", err)
+	}
+	t.Cleanup(func() { _ = watcher.Close() })
+
+	changed := make(chan bugfixes.Config, 1)
+	watcher.OnChange(func(cfg bugfixes.Config) { changed <- cfg })
+
+	if err := os.WriteFile(path, []byte("log_level: warn\n"), 0o600); err != nil {
+		t.Fatalf("rewrite config: %v", err)
+	}
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("find process: %v", err)
+	}
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		t.Fatalf("send SIGHUP: %v", err)
+	}
+
+	select {
+	case cfg := <-changed:
+		if cfg.LogLevel != bugfixes.WARN {
+			t.Fatalf("expected the new file, got %q", cfg.LogLevel)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected SIGHUP to reload the file")
+	}
+	if got := bugfixes.GetDefaultConfig().LogLevel; got != bugfixes.WARN {
+		t.Fatalf("expected the default config to be swapped, got %q", got)
+	}
+}